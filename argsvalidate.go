@@ -0,0 +1,76 @@
+package miniflags
+
+import "fmt"
+
+// ArgsValidator is a function that checks the list of non-option arguments
+// returned by ParseArgs, returning an error if the list is not acceptable.
+// The factory functions NoArgs, ExactArgs, MinimumNArgs and RangeArgs create
+// validators for the usual checks on the number of arguments.
+type ArgsValidator func(args []string) error
+
+// ValidateArgs adds a validator that is called with the non-option arguments
+// at the end of each successful parse, after any positional arguments have
+// been assigned. An error from the validator is reported in the same way as
+// any other parse error. Validators are called in the order they were added.
+// This can be used with the option set of a command to check the arguments
+// that will be passed to its handler. Returns self so that calls can be
+// chained.
+func (self *OptionSet) ValidateArgs(validators ...ArgsValidator) *OptionSet {
+	self.argsValidators = append(self.argsValidators, validators...)
+	return self
+}
+
+// Call each of the argument validators with args, returning the first error.
+func (self *OptionSet) checkArgs(args []string) error {
+	for _, validate := range self.argsValidators {
+		if err := validate(args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NoArgs returns a validator that accepts only an empty argument list.
+func NoArgs() ArgsValidator {
+	return RangeArgs(0, 0)
+}
+
+// ExactArgs returns a validator that accepts exactly n arguments.
+func ExactArgs(n int) ArgsValidator {
+	return RangeArgs(n, n)
+}
+
+// MinimumNArgs returns a validator that accepts n or more arguments.
+func MinimumNArgs(n int) ArgsValidator {
+	return RangeArgs(n, -1)
+}
+
+// RangeArgs returns a validator that accepts from min to max arguments. A max
+// less than zero means there is no limit. Errors read, for example,
+// "Expected at least 2 arguments" or "Expected from 1 to 3 arguments".
+func RangeArgs(min, max int) ArgsValidator {
+	return func(args []string) error {
+		n := len(args)
+		switch {
+		case n >= min && (max < 0 || n <= max):
+			return nil
+		case max == 0:
+			return fmt.Errorf("Expected no arguments, got %d", n)
+		case min == max:
+			return fmt.Errorf("Expected exactly %s, got %d", countArgs(min), n)
+		case max < 0:
+			return fmt.Errorf("Expected at least %s, got %d", countArgs(min), n)
+		default:
+			return fmt.Errorf("Expected from %d to %d arguments, got %d", min, max, n)
+		}
+	}
+}
+
+// Return a count of arguments with the noun in the right form, e.g.
+// "1 argument" or "2 arguments".
+func countArgs(n int) string {
+	if n == 1 {
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", n)
+}
@@ -0,0 +1,48 @@
+package miniflags
+
+import "testing"
+
+func Test_ArgsValidators(t *testing.T) {
+	var tests = []struct {
+		validator ArgsValidator
+		args      []string
+		errPrefix string
+	}{
+		{NoArgs(), []string{}, ""},
+		{NoArgs(), []string{"a"}, "Expected no arguments, got 1"},
+		{ExactArgs(1), []string{"a"}, ""},
+		{ExactArgs(1), []string{}, "Expected exactly 1 argument, got 0"},
+		{ExactArgs(2), []string{"a"}, "Expected exactly 2 arguments, got 1"},
+		{MinimumNArgs(2), []string{"a", "b", "c"}, ""},
+		{MinimumNArgs(2), []string{"a"}, "Expected at least 2 arguments, got 1"},
+		{RangeArgs(1, 3), []string{"a", "b"}, ""},
+		{RangeArgs(1, 3), []string{"a", "b", "c", "d"}, "Expected from 1 to 3 arguments, got 4"},
+	}
+	for _, test := range tests {
+		if m := checkValErr(t, nil, nil, test.errPrefix, test.validator(test.args)); m != "" {
+			t.Error(test.args, m)
+		}
+	}
+}
+
+func Test_OptionSet_ValidateArgs(t *testing.T) {
+	var verbose bool
+	var src string
+	var tests = []struct {
+		input     []string
+		want      []string
+		errPrefix string
+	}{
+		{[]string{"s", "a", "-v"}, []string{"a"}, ""},
+		{[]string{"s"}, []string{}, "Expected exactly 1 argument, got 0"},
+		{[]string{"s", "a", "b"}, []string{"a", "b"}, "Expected exactly 1 argument, got 2"},
+		{[]string{"s", "--", "-v"}, []string{"--", "-v"}, ""},
+	}
+	for _, test := range tests {
+		oset := NewOptionSet(Option("v verbose", &verbose, "")).Positional("SRC", &src, "").ValidateArgs(ExactArgs(1))
+		args, err := oset.ParseArgs(test.input)
+		if m := checkValErr(t, test.want, args, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package miniflags
+
+import "os"
+
+// HelpColors holds the ANSI escape sequences used to color help output. An
+// empty string leaves that part of the output uncolored.
+type HelpColors struct {
+	Names      string // Option, argument and command names
+	ValueNames string // Parameter names such as "=NUM"
+	Headers    string // Section headers and block titles such as "Options:"
+}
+
+// DefaultHelpColors is the palette used by ColorHelp when it is given nil:
+// cyan names, yellow parameter names and bold headers.
+var DefaultHelpColors = HelpColors{
+	Names:      "\x1b[36m",
+	ValueNames: "\x1b[33m",
+	Headers:    "\x1b[1m",
+}
+
+// The escape sequence that ends a colored span
+const colorReset = "\x1b[0m"
+
+// UseColor is called when help output is formatted for a set with ColorHelp
+// enabled, to decide whether colors should be used. The default returns true
+// if stderr is a terminal and the NO_COLOR environment variable is not set.
+// This function can be replaced by the client to substitute different
+// behavior, for example if Emit writes somewhere other than stderr.
+var UseColor = func() bool {
+	if _, ok := LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	info, err := os.Stderr.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// ColorHelp enables colored help output for this set using the given
+// palette, or DefaultHelpColors if colors is nil. Colors are only used when
+// UseColor returns true, so output that is redirected to a file or pipe stays
+// plain. Returns self so that calls can be chained.
+func (self *OptionSet) ColorHelp(colors *HelpColors) *OptionSet {
+	if colors == nil {
+		colors = &DefaultHelpColors
+	}
+	palette := *colors
+	self.colors = &palette
+	return self
+}
+
+// Return the palette to use for help output, or nil for plain output.
+func (self *OptionSet) helpColors() *HelpColors {
+	if self.colors == nil || !UseColor() {
+		return nil
+	}
+	return self.colors
+}
+
+// Return text wrapped in the escape sequence color and a reset, or text
+// unchanged if color is empty.
+func colorize(text, color string) string {
+	if color == "" || text == "" {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// Return the left column text of a help entry in color: the names, then any
+// parameter name, which starts with "=" or "[=".
+func (self *OptionSet) colorHelpLeft(left string) string {
+	colors := self.helpColors()
+	if colors == nil {
+		return left
+	}
+	names, valName := left, ""
+	for i := 0; i < len(left); i++ {
+		if left[i] == '=' || (left[i] == '[' && i+1 < len(left) && left[i+1] == '=') {
+			names, valName = left[:i], left[i:]
+			break
+		}
+	}
+	return colorize(names, colors.Names) + colorize(valName, colors.ValueNames)
+}
+
+// Return a header line of help output in color.
+func (self *OptionSet) colorHeader(header string) string {
+	if colors := self.helpColors(); colors != nil {
+		return colorize(header, colors.Headers)
+	}
+	return header
+}
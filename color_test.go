@@ -0,0 +1,46 @@
+package miniflags
+
+import "testing"
+
+func Test_OptionSet_ColorHelp(t *testing.T) {
+	savedUseColor := UseColor
+	defer func() { UseColor = savedUseColor }()
+	var num int
+	var color string
+	newSet := func(colors *HelpColors) *OptionSet {
+		return NewOptionSet().
+			Section("Main:").
+			Option("n number", &num, "=NUM; Number value").
+			Add(Option("color", &color, "=WHEN; Use color").Implicit("always")).
+			Add(Option("h", func() {}, "").Hide()).
+			ColorHelp(colors)
+	}
+	var tests = []struct {
+		useColor bool
+		colors   *HelpColors
+		want     []string
+	}{
+		{false, nil, []string{
+			"Main:",
+			"  -n, --number=NUM  Number value",
+			"  --color[=WHEN]    Use color",
+		}},
+		{true, nil, []string{
+			"\x1b[1mMain:\x1b[0m",
+			"  \x1b[36m-n, --number\x1b[0m\x1b[33m=NUM\x1b[0m  Number value",
+			"  \x1b[36m--color\x1b[0m\x1b[33m[=WHEN]\x1b[0m    Use color",
+		}},
+		{true, &HelpColors{Names: "<", ValueNames: ">"}, []string{
+			"Main:",
+			"  <-n, --number\x1b[0m>=NUM\x1b[0m  Number value",
+			"  <--color\x1b[0m>[=WHEN]\x1b[0m    Use color",
+		}},
+	}
+	for _, test := range tests {
+		useColor := test.useColor
+		UseColor = func() bool { return useColor }
+		if m := checkValErr(t, test.want, newSet(test.colors).FormatOptionsHelp(), "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package miniflags
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CommandDef structs are used to specify subcommands, such as the "build" in
+// "tool build -v". Each command has its own OptionSet for the options and
+// arguments that follow its name on the command line.
+type CommandDef struct {
+	names     []string    // The names that select this command; the first is canonical
+	set       *OptionSet  // The options and arguments of this command
+	handler   interface{} // The action performed by Run and Execute
+	help      string      // Description of this command in the usage help text
+	isDefault bool        // This command is used when no command is given
+}
+
+// Command returns a new CommandDef for adding to an OptionSet with
+// AddCommand. The names are separated by spaces, and any of them selects the
+// command on the command line, so aliases such as "remove rm delete" can be
+// given. Only the first name is shown in help output. The set holds the
+// options and positional arguments that the command accepts; if set is nil,
+// the command accepts no options. The handler is called by Run or Execute
+// with the command's remaining non-option arguments. It must be nil, or a
+// function with one of these types:
+//
+//	func(args []string) error
+//	func(ctx context.Context, args []string) error
+func Command(names string, set *OptionSet, handler interface{}, help string) *CommandDef {
+	if set == nil {
+		set = NewOptionSet()
+	}
+	return &CommandDef{names: strings.Fields(names), set: set, handler: handler, help: help}
+}
+
+// CommandSection returns a CommandDef that is a section header rather than a
+// command. The header text is shown in the list of commands in help output,
+// so that the commands after it form a named group such as
+// "Advanced commands:".
+func CommandSection(header string) *CommandDef {
+	return &CommandDef{help: header}
+}
+
+// Check if this entry is just a section header for help output
+func (self *CommandDef) isSectionHeader() bool {
+	return self.set == nil && len(self.names) == 0
+}
+
+// Name returns the canonical name of this command, which is the first of
+// its names.
+func (self *CommandDef) Name() string {
+	if len(self.names) == 0 {
+		return ""
+	}
+	return self.names[0]
+}
+
+// Default makes this the command that is used when no command name is given
+// on the command line, as if its name had been given after any options. The
+// command is still listed in help output, marked "(default)". Only one
+// command in a set may be the default. Returns self so that calls can be
+// chained.
+func (self *CommandDef) Default() *CommandDef {
+	self.isDefault = true
+	return self
+}
+
+// Options returns the OptionSet of this command.
+func (self *CommandDef) Options() *OptionSet {
+	return self.set
+}
+
+// AddCommand adds a number of subcommands to this option set. Once a set has
+// commands, the first non-option argument given to ParseArgs must be the name
+// of one of them, and the arguments after it are parsed with that command's
+// OptionSet. Options of this set are still recognized after the command name,
+// unless the command defines an option with the same name. If no command is
+// given and there is no default command (see Default), ParseArgs reports a
+// "Missing command" error. Duplicate names are saved as an error for
+// reporting when ParseArgs is called. Returns self so that calls can be
+// chained.
+func (self *OptionSet) AddCommand(cmds ...*CommandDef) *OptionSet {
+	for _, cmd := range cmds {
+		if cmd.isSectionHeader() {
+			self.commands = append(self.commands, cmd)
+			continue
+		}
+		if self.setupError == nil && len(cmd.names) == 0 {
+			self.setupError = fmt.Errorf("Invalid command name ''")
+		}
+		if self.setupError == nil && !cmd.isHandlerOk() {
+			self.setupError = fmt.Errorf("Unsupported handler type for command '%s'", cmd.Name())
+		}
+		if self.setupError == nil && cmd.isDefault && self.defaultCommand() != nil {
+			self.setupError = fmt.Errorf("Command '%s' is not the only default command", cmd.Name())
+		}
+		for _, name := range cmd.names {
+			if self.setupError == nil && self.findCommand(name) != nil {
+				self.setupError = fmt.Errorf("Command name '%s' defined more than once", name)
+			}
+		}
+		cmd.set.parent = self
+		self.commands = append(self.commands, cmd)
+	}
+	return self
+}
+
+// CommandSection is equivalent to calling AddCommand(CommandSection(header))
+// on this OptionSet. Returns self so that calls can be chained.
+func (self *OptionSet) CommandSection(header string) *OptionSet {
+	return self.AddCommand(CommandSection(header))
+}
+
+// Check that the command's handler has a supported type.
+func (self *CommandDef) isHandlerOk() bool {
+	switch self.handler.(type) {
+	case nil, func([]string) error, func(context.Context, []string) error:
+		return true
+	default:
+		return false
+	}
+}
+
+// Call the command's handler with ctx and args, unless ctx is already done.
+func (self *CommandDef) run(ctx context.Context, args []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	switch handler := self.handler.(type) {
+	case func([]string) error:
+		return handler(args)
+	case func(context.Context, []string) error:
+		return handler(ctx, args)
+	}
+	return nil
+}
+
+// Return the command with the given name, or nil if there is none.
+func (self *OptionSet) findCommand(name string) *CommandDef {
+	for _, cmd := range self.commands {
+		for _, cmdName := range cmd.names {
+			if cmdName == name {
+				return cmd
+			}
+		}
+	}
+	return nil
+}
+
+// Return the default command of this set, or nil if there is none.
+func (self *OptionSet) defaultCommand() *CommandDef {
+	for _, cmd := range self.commands {
+		if cmd.isDefault {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// Select cmd and parse args, the arguments after its name, with the
+// command's option set. Options of this set or its parents found in args are
+// recorded in the counts and sources of this parse as if they were given
+// here.
+func (self *parser) parseCommand(cmd *CommandDef, args []string, mode parseMode) ([]string, error) {
+	self.command = cmd
+	sub, rest, err := cmd.set.parse(args, mode)
+	self.sub = sub
+	if err != nil {
+		return rest, err
+	}
+	for def, source := range sub.sources {
+		if source.Kind == SourceCommandLine && !cmd.set.owns(def) {
+			self.counts[def]++
+			self.values[def] = append(self.values[def], sub.values[def]...)
+			self.sources[def] = source
+		}
+	}
+	return rest, nil
+}
+
+// Return the error for an unknown command name, suggesting the closest
+// command name if there is one that is likely to be a misspelling.
+func (self *OptionSet) unknownCommandError(name string) error {
+	best, bestDist := "", 0
+	for _, cmd := range self.commands {
+		for _, cmdName := range cmd.names {
+			if dist := editDistance(name, cmdName); best == "" || dist < bestDist {
+				best, bestDist = cmdName, dist
+			}
+		}
+	}
+	// allow about one edit for every three characters
+	if best != "" && bestDist <= (len(best)+2)/3 {
+		return fmt.Errorf("Unknown command '%s'; did you mean '%s'?", name, best)
+	}
+	return fmt.Errorf("Unknown command '%s'", name)
+}
+
+// Return the Levenshtein distance between a and b: the number of single
+// byte insertions, deletions and substitutions needed to change one into the
+// other.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// Return the smallest of three integers.
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// SelectedCommand returns the command that was chosen by the last call to
+// ParseArgs on this set, or nil if there was none.
+func (self *OptionSet) SelectedCommand() *CommandDef {
+	return self.command
+}
+
+// Run parses args as with ParseArgs, then calls the handler of the selected
+// command with the remaining non-option arguments, and returns its error.
+// Commands may have commands of their own to any depth, as in
+// "tool remote add URL"; the handler of the last command selected is called.
+// If the set has no commands, or the command has no handler, Run just parses
+// the arguments.
+func (self *OptionSet) Run(args []string) error {
+	p, rest, err := self.parse(args, parseMode{})
+	if err != nil {
+		return err
+	}
+	if cmd := p.leafCommand(); cmd != nil {
+		return cmd.run(context.Background(), rest)
+	}
+	return nil
+}
+
+// Execute parses args and calls the handler of the selected command in the
+// same way as Run, except that errors are returned rather than handled by
+// OnError, and ctx is passed to handlers that accept a context, so that
+// cancellation and deadlines reach them. If ctx is already done when the
+// handler would be called, its error is returned instead. As with ParseArgs,
+// if the automatic help option is given, the usage message is displayed and
+// ErrHelp is returned.
+func (self *OptionSet) Execute(ctx context.Context, args []string) error {
+	p, rest, err := self.parse(args, parseMode{returnErrors: true})
+	if err != nil {
+		return err
+	}
+	if cmd := p.leafCommand(); cmd != nil {
+		return cmd.run(ctx, rest)
+	}
+	return nil
+}
+
+// Return the last command selected by the parse, following nested commands
+// down from this one, or nil if no command was selected.
+func (self *parser) leafCommand() *CommandDef {
+	var cmd *CommandDef
+	for p := self; p != nil && p.command != nil; p = p.sub {
+		cmd = p.command
+	}
+	return cmd
+}
+
+// CommandPath returns the names of the commands selected by the last call to
+// ParseArgs on this set, from the outermost to the innermost, such as
+// []string{"remote", "add"}. The list is empty if no command was selected.
+func (self *OptionSet) CommandPath() []string {
+	path := []string{}
+	for set := self; set.command != nil; set = set.command.set {
+		path = append(path, set.command.Name())
+	}
+	return path
+}
+
+// Find an option by name in this set, or any set that this one is a command
+// of.
+func (self *OptionSet) lookupInherited(name string) *OptionDef {
+	for set := self; set != nil; set = set.parent {
+		if def := set.lookupDef(name); def != nil {
+			return def
+		}
+	}
+	return nil
+}
+
+// Check whether def is one of the options defined in this set.
+func (self *OptionSet) owns(def *OptionDef) bool {
+	for _, entry := range self.list {
+		if entry == def {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the names of the commands leading to this set from the outermost
+// set, separated by spaces, such as "remote add". Returns "" if this set is
+// not the option set of a command.
+func (self *OptionSet) commandName() string {
+	path := []string{}
+	for set := self; set.parent != nil; set = set.parent {
+		for _, cmd := range set.parent.commands {
+			if cmd.set == set {
+				path = append([]string{cmd.Name()}, path...)
+				break
+			}
+		}
+	}
+	return strings.Join(path, " ")
+}
+
+// Check whether the automatic "help" command is enabled for this set; see
+// AutoHelp.
+func (self *OptionSet) autoHelpCommand() bool {
+	return AutoHelp && len(self.commands) > 0 && self.findCommand("help") == nil
+}
+
+// Return the option set of the command named by the arguments of the
+// automatic "help" command, such as "remote add"; with no arguments, this is
+// self. Arguments starting with '-' are ignored.
+func (self *OptionSet) helpCommandSet(args []string) (*OptionSet, error) {
+	set := self
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			continue
+		}
+		cmd := set.findCommand(arg)
+		if cmd == nil {
+			return nil, set.unknownCommandError(arg)
+		}
+		set = cmd.set
+	}
+	return set, nil
+}
+
+// FormatGlobalOptionsHelp creates a list of lines of help output for the
+// options that the option set of a command inherits from the sets it is a
+// command of, formatted in the same way as FormatOptionsHelp. The list is
+// empty if this set is not the option set of a command.
+func (self *OptionSet) FormatGlobalOptionsHelp() []string {
+	out := []string{}
+	for set := self.parent; set != nil; set = set.parent {
+		out = append(out, set.formatOptionsHelp(false, false)...)
+	}
+	return out
+}
+
+// FormatCommandsHelp creates a list of lines of help output for the commands
+// of this set, formatted in the same way as FormatOptionsHelp. The list is
+// empty if no commands have been added.
+func (self *OptionSet) FormatCommandsHelp() []string {
+	out := []string{}
+	padding := self.helpPadding()
+	if self.alignSections {
+		lefts := []string{}
+		for _, cmd := range self.commands {
+			lefts = append(lefts, cmd.Name())
+		}
+		if self.autoHelpCommand() {
+			lefts = append(lefts, "help")
+		}
+		padding = self.fitPadding(lefts)
+	}
+	for _, cmd := range self.commands {
+		if cmd.isSectionHeader() {
+			out = append(out, self.colorHeader(cmd.help))
+			continue
+		}
+		help := cmd.help
+		if cmd.isDefault {
+			help = strings.TrimSpace(help + " (default)")
+		}
+		out = append(out, self.formatHelpEntry(cmd.Name(), help, padding)...)
+	}
+	if self.autoHelpCommand() {
+		out = append(out, self.formatHelpEntry("help", "Show help for a command", padding)...)
+	}
+	return out
+}
@@ -0,0 +1,440 @@
+package miniflags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_AddCommand(t *testing.T) {
+	var verbose, force bool
+	var level int
+	var ran string
+	var ranArgs []string
+	newSet := func() *OptionSet {
+		handler := func(name string) func([]string) error {
+			return func(args []string) error {
+				ran, ranArgs = name, args
+				return nil
+			}
+		}
+		return NewOptionSet(Option("v verbose", &verbose, "")).
+			AddCommand(
+				Command("build", NewOptionSet(Option("f force", &force, ""), Option("l level", &level, "")),
+					handler("build"), "Build the program"),
+				Command("clean", nil, handler("clean"), "Remove build outputs"))
+	}
+	var tests = []struct {
+		input     []string
+		wantVals  []interface{}
+		wantArgs  []string
+		errPrefix string
+	}{
+		{[]string{"build"}, []interface{}{false, false, 0, "build"}, []string{}, ""},
+		{[]string{"-v", "build", "-f", "x", "-l3"}, []interface{}{true, true, 3, "build"}, []string{"x"}, ""},
+		{[]string{"build", "-vf"}, []interface{}{true, true, 0, "build"}, []string{}, ""},
+		{[]string{"clean", "a", "b"}, []interface{}{false, false, 0, "clean"}, []string{"a", "b"}, ""},
+		{[]string{"-v"}, []interface{}{true, false, 0, ""}, nil, "Missing command"},
+		{[]string{"bulid"}, []interface{}{false, false, 0, ""}, nil, "Unknown command 'bulid'"},
+		{[]string{"clean", "-f"}, []interface{}{false, false, 0, ""}, nil, "Unknown option '-f'"},
+		{[]string{"-f", "build"}, []interface{}{false, false, 0, ""}, nil, "Unknown option '-f'"},
+	}
+	for _, test := range tests {
+		verbose, force, level, ran, ranArgs = false, false, 0, "", nil
+		err := newSet().Run(test.input)
+		if m := checkValErr(t, test.wantVals, []interface{}{verbose, force, level, ran}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if m := checkValErr(t, test.wantArgs, ranArgs, "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionSet_AddCommand_Sources(t *testing.T) {
+	var verbose, force bool
+	oset := NewOptionSet(Option("v verbose", &verbose, "").Required()).
+		AddCommand(Command("build", NewOptionSet(Option("f force", &force, "")), nil, ""))
+	_, err := oset.ParseArgs([]string{"build", "--verbose"})
+	if m := checkValErr(t, ValueSource{SourceCommandLine, "--verbose"}, oset.Source("verbose"), "", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, "build", oset.SelectedCommand().Name(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_AddCommand_Errors(t *testing.T) {
+	var tests = []struct {
+		oset      *OptionSet
+		errPrefix string
+	}{
+		{NewOptionSet().AddCommand(Command("a", nil, nil, ""), Command("a", nil, nil, "")), "Command name 'a' defined more than once"},
+		{NewOptionSet().AddCommand(Command("", nil, nil, "")), "Invalid command name ''"},
+	}
+	for _, test := range tests {
+		_, err := test.oset.ParseArgs([]string{"a"})
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionSet_FormatCommandsHelp(t *testing.T) {
+	oset := NewOptionSet().AddCommand(
+		Command("build", nil, nil, "Build the program"),
+		Command("a-very-long-command", nil, nil, "Long"))
+	want := strings.Join([]string{
+		"  build             Build the program",
+		"  a-very-long-command",
+		"                    Long",
+		"  help              Show help for a command",
+	}, "\n")
+	if m := checkValErr(t, want, strings.Join(oset.FormatCommandsHelp(), "\n"), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_AddCommand_Nested(t *testing.T) {
+	var verbose, track bool
+	var ran []string
+	handler := func(name string) func([]string) error {
+		return func(args []string) error {
+			ran = append([]string{name}, args...)
+			return nil
+		}
+	}
+	newSet := func() *OptionSet {
+		remote := NewOptionSet().AddCommand(
+			Command("add", NewOptionSet(Option("t track", &track, "")), handler("add"), ""),
+			Command("remove", nil, handler("remove"), ""))
+		return NewOptionSet(Option("v verbose", &verbose, "")).
+			AddCommand(Command("remote", remote, nil, ""), Command("status", nil, handler("status"), ""))
+	}
+	var tests = []struct {
+		input     []string
+		wantVals  []interface{}
+		wantPath  []string
+		errPrefix string
+	}{
+		{[]string{"remote", "add", "-t", "origin", "-v"}, []interface{}{true, true, []string{"add", "origin"}}, []string{"remote", "add"}, ""},
+		{[]string{"remote", "-v", "remove", "origin"}, []interface{}{true, false, []string{"remove", "origin"}}, []string{"remote", "remove"}, ""},
+		{[]string{"status"}, []interface{}{false, false, []string{"status"}}, []string{"status"}, ""},
+		{[]string{"remote"}, []interface{}{false, false, []string(nil)}, []string{"remote"}, "Missing command"},
+		{[]string{"remote", "status"}, []interface{}{false, false, []string(nil)}, []string{"remote"}, "Unknown command 'status'"},
+	}
+	for _, test := range tests {
+		verbose, track, ran = false, false, nil
+		oset := newSet()
+		err := oset.Run(test.input)
+		if m := checkValErr(t, test.wantVals, []interface{}{verbose, track, ran}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if m := checkValErr(t, test.wantPath, oset.CommandPath(), "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionSet_AddCommand_NestedSources(t *testing.T) {
+	var verbose bool
+	remote := NewOptionSet().AddCommand(Command("add", nil, nil, ""))
+	oset := NewOptionSet(Option("v verbose", &verbose, "").Required()).
+		AddCommand(Command("remote", remote, nil, ""))
+	_, err := oset.ParseArgs([]string{"remote", "add", "-v"})
+	if m := checkValErr(t, ValueSource{SourceCommandLine, "-v"}, oset.Source("verbose"), "", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_Command_Aliases(t *testing.T) {
+	var ran string
+	newSet := func() *OptionSet {
+		return NewOptionSet().AddCommand(
+			Command("remove rm delete", nil, func([]string) error { ran = "remove"; return nil }, "Remove files"),
+			Command("list ls", nil, func([]string) error { ran = "list"; return nil }, "List files"))
+	}
+	var tests = []struct {
+		input     string
+		want      string
+		errPrefix string
+	}{
+		{"remove", "remove", ""},
+		{"rm", "remove", ""},
+		{"delete", "remove", ""},
+		{"ls", "list", ""},
+		{"del", "", "Unknown command 'del'"},
+	}
+	for _, test := range tests {
+		ran = ""
+		oset := newSet()
+		err := oset.Run([]string{test.input})
+		if m := checkValErr(t, test.want, ran, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if test.errPrefix == "" {
+			if m := checkValErr(t, []string{test.want}, oset.CommandPath(), "", nil); m != "" {
+				t.Error(test.input, m)
+			}
+		}
+	}
+	want := "  remove            Remove files\n  list              List files\n  help              Show help for a command"
+	if m := checkValErr(t, want, strings.Join(newSet().FormatCommandsHelp(), "\n"), "", nil); m != "" {
+		t.Error(m)
+	}
+	_, err := NewOptionSet().AddCommand(Command("remove rm", nil, nil, ""), Command("rm", nil, nil, "")).ParseArgs([]string{"rm"})
+	if m := checkValErr(t, nil, nil, "Command name 'rm' defined more than once", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_Command_Default(t *testing.T) {
+	var port int
+	var ran string
+	newSet := func() *OptionSet {
+		return NewOptionSet().AddCommand(
+			Command("status", nil, func([]string) error { ran = "status"; return nil }, "Show status"),
+			Command("serve", NewOptionSet(Option("p port", &port, "")), func([]string) error { ran = "serve"; return nil }, "Run the server").Default())
+	}
+	var tests = []struct {
+		input     []string
+		want      []interface{}
+		errPrefix string
+	}{
+		{[]string{}, []interface{}{"serve", 0}, ""},
+		{[]string{"status"}, []interface{}{"status", 0}, ""},
+		{[]string{"serve", "-p", "80"}, []interface{}{"serve", 80}, ""},
+		{[]string{"-p", "80"}, []interface{}{"", 0}, "Unknown option '-p'"},
+	}
+	for _, test := range tests {
+		ran, port = "", 0
+		err := newSet().Run(test.input)
+		if m := checkValErr(t, test.want, []interface{}{ran, port}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+	want := "  status            Show status\n  serve             Run the server (default)\n  help              Show help for a command"
+	if m := checkValErr(t, want, strings.Join(newSet().FormatCommandsHelp(), "\n"), "", nil); m != "" {
+		t.Error(m)
+	}
+	_, err := NewOptionSet().AddCommand(Command("a", nil, nil, "").Default(), Command("b", nil, nil, "").Default()).ParseArgs(nil)
+	if m := checkValErr(t, nil, nil, "Command 'b' is not the only default command", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_unknownCommandError(t *testing.T) {
+	oset := NewOptionSet().AddCommand(
+		Command("status st", nil, nil, ""),
+		Command("commit", nil, nil, ""),
+		Command("remove rm", nil, nil, ""))
+	var tests = []struct {
+		input string
+		want  string
+	}{
+		{"stauts", "Unknown command 'stauts'; did you mean 'status'?"},
+		{"comit", "Unknown command 'comit'; did you mean 'commit'?"},
+		{"rn", "Unknown command 'rn'; did you mean 'rm'?"},
+		{"push", "Unknown command 'push'"},
+		{"x", "Unknown command 'x'"},
+	}
+	for _, test := range tests {
+		_, err := oset.ParseArgs([]string{test.input})
+		if m := checkValErr(t, test.want, err.Error(), "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_editDistance(t *testing.T) {
+	var tests = []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"status", "stauts", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, test := range tests {
+		if m := checkValErr(t, test.want, editDistance(test.a, test.b), "", nil); m != "" {
+			t.Error(test.a, test.b, m)
+		}
+	}
+}
+
+func Test_OptionSet_Usage_Command(t *testing.T) {
+	var verbose, force bool
+	var files []string
+	add := NewOptionSet(Option("f force", &force, "Overwrite files")).Positional("FILE", &files, "")
+	build := NewOptionSet(Option("f force", &force, "Rebuild everything"))
+	remote := NewOptionSet().AddCommand(Command("add", add, nil, ""))
+	NewOptionSet(Option("v verbose", &verbose, "Verbose output")).
+		AddCommand(Command("build", build, nil, ""), Command("remote", remote, nil, ""))
+
+	var lines []string
+	savedEmit := Emit
+	defer func() { Emit = savedEmit }()
+	Emit = func(a ...interface{}) {
+		lines = append(lines, strings.TrimSuffix(fmt.Sprintln(a...), "\n"))
+	}
+	var tests = []struct {
+		oset *OptionSet
+		want []string
+	}{
+		{build, []string{
+			"Usage: PROG build [ options and/or arguments ]",
+			"Options:",
+			"  -f, --force       Rebuild everything",
+			"  -h, --help        Print this help message and exit",
+			"Global options:",
+			"  -v, --verbose     Verbose output",
+		}},
+		{add, []string{
+			"Usage: PROG remote add [ options ] FILE...",
+			"Arguments:",
+			"  FILE...           ",
+			"Options:",
+			"  -f, --force       Overwrite files",
+			"  -h, --help        Print this help message and exit",
+			"Global options:",
+			"  -v, --verbose     Verbose output",
+		}},
+	}
+	prog := filepath.Base(os.Args[0])
+	for _, test := range tests {
+		lines = nil
+		Usage(test.oset)
+		want := strings.Replace(strings.Join(test.want, "\n"), "PROG", prog, 1)
+		if m := checkValErr(t, want, strings.Join(lines, "\n"), "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionSet_CommandSection(t *testing.T) {
+	var ran string
+	handler := func(name string) func([]string) error {
+		return func([]string) error { ran = name; return nil }
+	}
+	oset := NewOptionSet().
+		CommandSection("Basic commands:").
+		AddCommand(Command("init", nil, handler("init"), "Create a repository")).
+		CommandSection("Advanced commands:").
+		AddCommand(Command("gc", nil, handler("gc"), "Clean up"))
+	want := strings.Join([]string{
+		"Basic commands:",
+		"  init              Create a repository",
+		"Advanced commands:",
+		"  gc                Clean up",
+		"  help              Show help for a command",
+	}, "\n")
+	if m := checkValErr(t, want, strings.Join(oset.FormatCommandsHelp(), "\n"), "", nil); m != "" {
+		t.Error(m)
+	}
+	err := oset.Run([]string{"gc"})
+	if m := checkValErr(t, "gc", ran, "", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Execute(t *testing.T) {
+	type key struct{}
+	var got interface{}
+	newSet := func() *OptionSet {
+		return NewOptionSet().AddCommand(
+			Command("get", nil, func(ctx context.Context, args []string) error {
+				got = ctx.Value(key{})
+				return nil
+			}, ""),
+			Command("fail", nil, func([]string) error { return fmt.Errorf("Failed") }, ""))
+	}
+	savedOnError, savedEmit := OnError, Emit
+	defer func() { OnError, Emit = savedOnError, savedEmit }()
+	reported := false
+	OnError = func(*OptionSet, ...interface{}) { reported = true }
+	Emit = func(...interface{}) {}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	var tests = []struct {
+		ctx       context.Context
+		input     []string
+		want      interface{}
+		errPrefix string
+	}{
+		{context.WithValue(context.Background(), key{}, "v"), []string{"get"}, "v", ""},
+		{canceled, []string{"get"}, nil, "context canceled"},
+		{context.Background(), []string{"fail"}, nil, "Failed"},
+		{context.Background(), []string{"got"}, nil, "Unknown command 'got'; did you mean 'get'?"},
+		{context.Background(), []string{"get", "-x"}, nil, "Unknown option '-x'"},
+		{context.Background(), []string{"-h"}, nil, "Help requested"},
+		{context.Background(), []string{"get", "--help"}, nil, "Help requested"},
+	}
+	for _, test := range tests {
+		got = nil
+		err := newSet().Execute(test.ctx, test.input)
+		if m := checkValErr(t, test.want, got, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+	if reported {
+		t.Error("OnError was called by Execute")
+	}
+	_, err := NewOptionSet().AddCommand(Command("a", nil, func() {}, "")).ParseArgs([]string{"a"})
+	if m := checkValErr(t, nil, nil, "Unsupported handler type for command 'a'", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_UsageHeader(t *testing.T) {
+	copySet := NewOptionSet().UsageHeader("Usage: tool copy [ options ] SRC DST")
+	buildSet := NewOptionSet()
+	oset := NewOptionSet().AddCommand(Command("copy", copySet, nil, ""), Command("build", buildSet, nil, ""))
+	prog := filepath.Base(os.Args[0])
+	var tests = []struct {
+		oset *OptionSet
+		want string
+	}{
+		{copySet, "Usage: tool copy [ options ] SRC DST"},
+		{buildSet, "Usage: " + prog + " build [ options and/or arguments ]"},
+		{oset, "Usage: " + prog + " [ options ] COMMAND [ arguments ]"},
+	}
+	for _, test := range tests {
+		if m := checkValErr(t, test.want, test.oset.usageHeader(), "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionSet_HelpCommand(t *testing.T) {
+	var force bool
+	build := NewOptionSet(Option("f force", &force, "Rebuild everything"))
+	remote := NewOptionSet().AddCommand(Command("add", nil, nil, ""))
+	oset := NewOptionSet().AddCommand(Command("build", build, nil, ""), Command("remote", remote, nil, ""))
+
+	var shown *OptionSet
+	savedUsage := Usage
+	defer func() { Usage = savedUsage }()
+	Usage = func(set *OptionSet) { shown = set }
+	var tests = []struct {
+		input     []string
+		want      *OptionSet
+		errPrefix string
+	}{
+		{[]string{"help"}, oset, "Help requested"},
+		{[]string{"help", "build"}, build, "Help requested"},
+		{[]string{"help", "remote", "add"}, remote.commands[0].set, "Help requested"},
+		{[]string{"help", "biuld"}, nil, "Unknown command 'biuld'; did you mean 'build'?"},
+	}
+	for _, test := range tests {
+		shown = nil
+		err := oset.Execute(context.Background(), test.input)
+		if m := checkValErr(t, true, test.want == shown, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
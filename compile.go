@@ -0,0 +1,58 @@
+package miniflags
+
+import (
+	"sort"
+)
+
+// A matcher holds precomputed tables for finding options by name without
+// map lookups.
+type matcher struct {
+	shorts    [256]*OptionDef // Options with single-byte names, indexed by the byte
+	longNames []string        // Longer names in sorted order
+	longDefs  []*OptionDef    // The options named in longNames, in the same order
+}
+
+// Compile precomputes lookup tables for the option names in set and returns
+// set. After compiling, ParseArgs looks up option names without a map, finding
+// single-character options by direct table indexing and long options by binary
+// search, which keeps parse time low for startup-critical programs with many
+// options.
+// Compile should be called after all options have been added; adding more
+// options discards the tables, and the set then works as before until Compile
+// is called again.
+func Compile(set *OptionSet) *OptionSet {
+	m := &matcher{}
+	names := make([]string, 0, len(set.index))
+	for name, def := range set.index {
+		if len(name) == 1 {
+			m.shorts[name[0]] = def
+		} else {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	m.longNames = names
+	m.longDefs = make([]*OptionDef, len(names))
+	for i, name := range names {
+		m.longDefs[i] = set.index[name]
+	}
+	set.matcher = m
+	return set
+}
+
+// Return the option that has the given name, or nil if there is none. Uses
+// the compiled matcher if there is one, otherwise the index map.
+func (self *OptionSet) findName(name string) *OptionDef {
+	m := self.matcher
+	if m == nil {
+		return self.index[name]
+	}
+	if len(name) == 1 {
+		return m.shorts[name[0]]
+	}
+	i := sort.SearchStrings(m.longNames, name)
+	if i < len(m.longNames) && m.longNames[i] == name {
+		return m.longDefs[i]
+	}
+	return nil
+}
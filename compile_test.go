@@ -0,0 +1,72 @@
+package miniflags
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_Compile(t *testing.T) {
+	var n int
+	var b bool
+	oset := Compile(NewOptionSet().
+		Option("n number", &n, "").
+		Option("b", &b, "").
+		Option("é", func() {}, "").
+		Negations())
+	var tests = []struct {
+		name string
+		want *OptionDef
+	}{
+		{"n", oset.index["n"]},
+		{"number", oset.index["n"]},
+		{"b", oset.index["b"]},
+		{"é", oset.index["é"]},
+		{"x", nil},
+		{"num", nil},
+		{"numbers", nil},
+		{"zzz", nil},
+	}
+	for _, test := range tests {
+		if m := checkValErr(t, test.want, oset.lookupDef(test.name), "", nil); m != "" {
+			t.Error(test.name, m)
+		}
+	}
+	args, err := oset.ParseArgs([]string{"-bn3", "a", "--number", "4"})
+	if m := checkValErr(t, []string{"a"}, args, "", err); m != "" {
+		t.Error(m)
+	}
+	if n != 4 || !b {
+		t.Errorf("Got n=%d, b=%v; expected 4, true", n, b)
+	}
+
+	// adding options discards the compiled tables
+	oset.Option("x", func() {}, "")
+	if oset.matcher != nil || oset.lookupDef("x") == nil {
+		t.Error("Compiled matcher not discarded by Add")
+	}
+}
+
+// build a set with many long options for benchmarking
+func benchmarkSet() *OptionSet {
+	oset := NewOptionSet()
+	for i := 0; i < 300; i++ {
+		oset.Option(fmt.Sprintf("option-%d", i), func(string) {}, "")
+	}
+	return oset
+}
+
+var benchmarkArgs = []string{"--option-1=a", "--option-150", "b", "--option-299=c", "x", "y"}
+
+func Benchmark_ParseArgs(b *testing.B) {
+	oset := benchmarkSet()
+	for i := 0; i < b.N; i++ {
+		oset.ParseArgs(benchmarkArgs)
+	}
+}
+
+func Benchmark_ParseArgs_compiled(b *testing.B) {
+	oset := Compile(benchmarkSet())
+	for i := 0; i < b.N; i++ {
+		oset.ParseArgs(benchmarkArgs)
+	}
+}
@@ -0,0 +1,454 @@
+package miniflags
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A value for an option loaded from a config file.
+type configValue struct {
+	file   string   // The name of the file
+	key    string   // The key as written in the file
+	where  string   // The file name and line number, for messages
+	values []string // The values in order; more than one for a list option
+}
+
+// The values read from one config file, kept until the whole file has been
+// read without errors.
+type configBatch struct {
+	file     string // The name of the file
+	values   map[*OptionDef]*configValue
+	order    []*OptionDef // The options in the order they were first found
+	warnings []string     // Unknown keys and sections allowed by WarnUnknownConfig
+}
+
+// Add a value for def to the batch. A list option collects all its values,
+// and for other options the last value is kept.
+func (self *configBatch) add(def *OptionDef, key, where, value string) {
+	if self.values == nil {
+		self.values = map[*OptionDef]*configValue{}
+	}
+	if self.values[def] == nil {
+		self.values[def] = &configValue{file: self.file, key: key, where: where}
+		self.order = append(self.order, def)
+	}
+	if def.isList() {
+		self.values[def].values = append(self.values[def].values, value)
+	} else {
+		self.values[def].values = []string{value}
+	}
+}
+
+// Put the values of the batch in config, replacing any loaded earlier.
+func (self *configBatch) store(config map[*OptionDef]*configValue) {
+	for _, def := range self.order {
+		config[def] = self.values[def]
+	}
+}
+
+// Report the warnings for a config file read into batch, and keep its values
+// in the set if there was no error. Returns err.
+func (self *OptionSet) keepConfig(batch *configBatch, err error) error {
+	if batch != nil {
+		for _, message := range batch.warnings {
+			self.warn(message)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if self.config == nil {
+		self.config = map[*OptionDef]*configValue{}
+	}
+	batch.store(self.config)
+	return nil
+}
+
+// WarnUnknownConfig makes unknown keys and sections in the config files read
+// by this set warnings rather than errors, so that a file shared with a newer
+// version of the program can still be used. Each one is reported by passing a
+// message such as "Warning: Unknown option 'x' in config file tool.ini:3" to
+// the Emit method, and is otherwise ignored. Returns self so that calls can be
+// chained.
+func (self *OptionSet) WarnUnknownConfig() *OptionSet {
+	self.warnUnknownConfig = true
+	return self
+}
+
+// Check an unknown key or section in a config file read into batch. Returns
+// err, or nil if it is only a warning, which is added to the batch.
+func (self *OptionSet) unknownConfig(batch *configBatch, err error) error {
+	if !self.warnUnknownConfig {
+		return err
+	}
+	batch.warnings = append(batch.warnings, err.Error())
+	return nil
+}
+
+// LoadINI reads the INI file at path and keeps the values in it for the
+// options of this set. See ReadINI.
+func (self *OptionSet) LoadINI(path string) error {
+	return self.keepConfig(self.loadINI(path))
+}
+
+// Read the INI file at path as with readINI.
+func (self *OptionSet) loadINI(path string) (*configBatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return self.readINI(file, path)
+}
+
+// ReadINI reads a config file in INI format from r, and keeps the values in
+// it for the options of this set; name is the file name used in error
+// messages. Each key is the long name of an option, and is followed by "="
+// and the value, which is handled as if it were the option's parameter, with
+// a bool target set to the parsed value as for Env. A key without a value
+// sets an option that takes no parameter. Repeating the key of a list option
+// adds each value. Keys after a "[section]" line must name an option in the
+// section of the set with a matching header, ignoring case, a trailing colon,
+// and spaces versus dashes, so that "[output options]" matches the section
+// "Output options:". Unknown keys and sections are errors unless
+// WarnUnknownConfig is used. Blank lines and lines starting with '#' or ';' are
+// ignored, and a value may be enclosed in double quotes.
+//
+// The values are not applied to the targets until ParseArgs is called, when
+// they are used for the options that were not given on the command line or by
+// an environment variable, and conversion errors are reported. Values read
+// later replace those read earlier for the same option, so several files can
+// be loaded in order of priority. See Precedence to change the priority of
+// config files relative to other sources. Returns an error for a syntax error
+// or unknown key.
+func (self *OptionSet) ReadINI(r io.Reader, name string) error {
+	return self.keepConfig(self.readINI(r, name))
+}
+
+// Read a config file in INI format as described for ReadINI. Returns the
+// values and warnings read, and any error.
+func (self *OptionSet) readINI(r io.Reader, name string) (*configBatch, error) {
+	batch := &configBatch{file: name}
+	section := ""
+	skipping := false // in an unknown section whose keys are ignored
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		where := fmt.Sprintf("%s:%d", name, lineNum)
+		switch {
+		case line == "" || line[0] == '#' || line[0] == ';':
+			continue
+		case line[0] == '[':
+			if !strings.HasSuffix(line, "]") {
+				return batch, fmt.Errorf("Invalid section line in config file %s", where)
+			}
+			section = configSectionName(line[1 : len(line)-1])
+			skipping = section != "" && !self.hasSection(section)
+			if skipping {
+				if err := self.unknownConfig(batch, fmt.Errorf("Unknown section '%s' in config file %s", line, where)); err != nil {
+					return batch, err
+				}
+			}
+			continue
+		}
+		key, value := line, ""
+		hasValue := false
+		if eq := strings.IndexByte(line, '='); eq >= 0 {
+			key, value, hasValue = strings.TrimSpace(line[:eq]), strings.TrimSpace(line[eq+1:]), true
+			if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+				value = value[1 : len(value)-1]
+			}
+		}
+		if skipping {
+			continue
+		}
+		def := self.findConfigKey(section, key)
+		switch {
+		case def == nil:
+			if err := self.unknownConfig(batch, fmt.Errorf("Unknown option '%s' in config file %s", key, where)); err != nil {
+				return batch, err
+			}
+			continue
+		case !hasValue && def.takesParameter():
+			return batch, fmt.Errorf("Missing value for '%s' in config file %s", key, where)
+		case !hasValue:
+			value = "true"
+		}
+		batch.add(def, key, where, value)
+	}
+	return batch, scanner.Err()
+}
+
+// Return the section name as used in a config file for a section header
+// such as "Output options:", which is "output-options".
+func configSectionName(header string) string {
+	header = strings.TrimSuffix(strings.TrimSpace(header), ":")
+	return strings.ToLower(strings.Join(strings.FieldsFunc(header, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == '-' || r == '_'
+	}), "-"))
+}
+
+// Check whether this set has a section whose header matches the config file
+// section name.
+func (self *OptionSet) hasSection(section string) bool {
+	for _, def := range self.list {
+		if def.isSectionHeader() && configSectionName(def.help) == section {
+			return true
+		}
+	}
+	return false
+}
+
+// Find the option with the long name key in the config file section, or in
+// any section if section is "". Returns nil if there is none.
+func (self *OptionSet) findConfigKey(section, key string) *OptionDef {
+	current := ""
+	for _, def := range self.list {
+		if def.isSectionHeader() {
+			current = configSectionName(def.help)
+			continue
+		}
+		if section != "" && current != section {
+			continue
+		}
+		for _, name := range strings.Fields(def.names) {
+			if len(name) > 1 && name == key {
+				return def
+			}
+		}
+	}
+	return nil
+}
+
+// Set def from the values loaded from config files, if there are any. If
+// given is true, the option was given on the command line, and its target is
+// reset to the default first. Returns true if there were values, and any
+// error from converting a value.
+func (self *parser) applyConfig(def *OptionDef, given bool) (bool, error) {
+	loaded := self.config[def]
+	if loaded == nil {
+		return false, nil
+	}
+	if given {
+		def.restoreDefault()
+	}
+	for _, value := range loaded.values {
+		if err := def.setExplicit(value, self); err != nil {
+			return false, &ErrBadValue{Option: def.displayName(), Value: value,
+				Source: ValueSource{SourceConfig, loaded.key}, Err: err, where: loaded.where}
+		}
+	}
+	self.sources[def] = ValueSource{SourceConfig, loaded.key}
+	return true, nil
+}
+
+// ConfigFileOption is a factory function that can be called to create an
+// Option target value for naming a config file, such as "--config". When the
+// option is given, the file is read for set as with LoadConfig once all the
+// arguments have been handled, so the parameter may also be a URL, and an
+// error reading it is reported as an error with the option. The values from
+// the file are applied at the end of the parse to the options that were not
+// given on the command line, so options given before or after it still take
+// precedence. If the option is
+// given more than once, values from later files replace those from earlier
+// ones. The values only apply to the parse in which the option is given, so
+// a later parse of the same set without the option doesn't use them. For
+// example:
+//
+//	oset.Option("c config", ConfigFileOption(oset), "=FILE; Read options from FILE")
+func ConfigFileOption(set *OptionSet) Setter {
+	return &configFile{set}
+}
+
+// The target value created by ConfigFileOption.
+type configFile struct {
+	set *OptionSet // The set the file is read for
+}
+
+// Read the config file at path into the set, when it is not being parsed.
+func (self *configFile) Set(path string) error {
+	return self.set.LoadConfig(path)
+}
+
+// Name the config file at path with the option def in the parse of the set,
+// which is p or a parse that p is a command of, to be read by
+// loadConfigFiles. If the set is not being parsed, the file is read into the
+// set now.
+func (self *configFile) load(path string, def *OptionDef, p *parser) error {
+	if p = p.forSet(self.set); p == nil {
+		return self.Set(path)
+	}
+	p.configFiles = append(p.configFiles, namedConfig{path, def})
+	return nil
+}
+
+// A config file named by an option during a parse.
+type namedConfig struct {
+	path string
+	def  *OptionDef // The option that named it
+}
+
+// Read the config files named by options during the parse, once all the
+// arguments have been handled, so that options given after them, such as a
+// ConfigAuthOption, apply to them. Returns an error with the option for the
+// first file that can't be read.
+func (self *parser) loadConfigFiles() error {
+	for _, named := range self.configFiles {
+		if err := self.loadConfig(named.path); err != nil {
+			return &ErrBadValue{Option: named.def.displayName(), Value: named.path,
+				Source: self.sources[named.def], Err: err}
+		}
+	}
+	return nil
+}
+
+// Read the config file at path as with LoadConfig, keeping its values for
+// this parse only. The authorization given by a ConfigAuthOption in this
+// parse, if any, is used instead of that of the set.
+func (self *parser) loadConfig(path string) error {
+	auth := self.set.configAuth
+	if self.configAuth != nil {
+		auth = *self.configAuth
+	}
+	batch, err := self.set.loadConfig(path, auth)
+	if batch != nil {
+		for _, message := range batch.warnings {
+			self.warn(message)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	batch.store(self.config)
+	return nil
+}
+
+// LoadConfig reads the config file at path with LoadJSON if its name ends in
+// ".json", or LoadINI otherwise. If path is an "http" or "https" URL, the
+// file is fetched with LoadConfigURL instead.
+func (self *OptionSet) LoadConfig(path string) error {
+	return self.keepConfig(self.loadConfig(path, self.configAuth))
+}
+
+// Read the config file at path as described for LoadConfig, sending auth as
+// the Authorization header if it is fetched from a URL.
+func (self *OptionSet) loadConfig(path, auth string) (*configBatch, error) {
+	if isConfigURL(path) {
+		return self.loadConfigURL(path, auth)
+	}
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		return self.loadJSON(path)
+	}
+	return self.loadINI(path)
+}
+
+// ConfigDirs returns the directories searched for the config files of the
+// program prog, in order, following the XDG Base Directory specification:
+// prog in $XDG_CONFIG_HOME (by default ~/.config), prog in each directory of
+// $XDG_CONFIG_DIRS (by default /etc/xdg), and then /etc/prog. The variables
+// are read with LookupEnv.
+func ConfigDirs(prog string) []string {
+	dirs := []string{}
+	if home, ok := LookupEnv("XDG_CONFIG_HOME"); ok && filepath.IsAbs(home) {
+		dirs = append(dirs, filepath.Join(home, prog))
+	} else if home, ok := LookupEnv("HOME"); ok && home != "" {
+		dirs = append(dirs, filepath.Join(home, ".config", prog))
+	}
+	systemDirs, ok := LookupEnv("XDG_CONFIG_DIRS")
+	if !ok || systemDirs == "" {
+		systemDirs = "/etc/xdg"
+	}
+	for _, dir := range filepath.SplitList(systemDirs) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, filepath.Join(dir, prog))
+		}
+	}
+	return append(dirs, filepath.Join("/etc", prog))
+}
+
+// LoadDefaultConfig searches the directories returned by ConfigDirs for a
+// config file of the program prog, and loads the first one found with
+// LoadConfig. In each directory, the file names are tried in order; if none
+// are given, they are "config.ini" and "config.json". Returns the path of the
+// file loaded, or "" if none was found, and any error loading it.
+func (self *OptionSet) LoadDefaultConfig(prog string, names ...string) (string, error) {
+	if len(names) == 0 {
+		names = []string{"config.ini", "config.json"}
+	}
+	for _, dir := range ConfigDirs(prog) {
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, self.LoadConfig(path)
+			}
+		}
+	}
+	return "", nil
+}
+
+// FormatConfigINI creates a list of lines of a sample INI config file for
+// this set, which users can save and edit as a starting point. Each option
+// with a long name and a variable or Setter target is listed under its
+// section, with its help text as a comment, followed by a commented-out line
+// setting the key to the option's default value. For example:
+//
+//	oset.Option("dump-config", func() {
+//		for _, line := range oset.FormatConfigINI() {
+//			fmt.Println(line)
+//		}
+//		os.Exit(0)
+//	}, "Print a sample config file and exit")
+func (self *OptionSet) FormatConfigINI() []string {
+	out := []string{}
+	for _, def := range self.list {
+		if def.isSectionHeader() {
+			out = append(out, "", "["+configSectionName(def.help)+"]")
+			continue
+		}
+		switch def.target.(type) {
+		case *configFile, *configAuthTarget:
+			continue
+		case *string, *uint, *uint64, *int, *int64, *float64, *bool, *[]string, Setter:
+		default:
+			continue
+		}
+		name := def.canonicalName()
+		if len(name) < 2 || def.hidden {
+			continue
+		}
+		_, help := def.splitHelp()
+		out = append(out, "")
+		if help = strings.TrimSpace(def.fullHelp(help, false)); help != "" {
+			for _, line := range strings.Split(help, "\n") {
+				out = append(out, strings.TrimRight("# "+strings.TrimSpace(line), " "))
+			}
+		}
+		values := []string{""}
+		switch value := def.defaultValue.(type) {
+		case nil:
+			if stringer, ok := def.target.(fmt.Stringer); ok {
+				values = []string{stringer.String()}
+			}
+		case []string:
+			if len(value) > 0 {
+				values = value
+			}
+		default:
+			values = []string{fmt.Sprint(value)}
+		}
+		for _, value := range values {
+			if value != strings.TrimSpace(value) || strings.HasPrefix(value, `"`) {
+				value = `"` + value + `"`
+			}
+			out = append(out, strings.TrimRight("#"+name+" = "+value, " "))
+		}
+	}
+	if len(out) > 0 && out[0] == "" {
+		out = out[1:]
+	}
+	return out
+}
@@ -0,0 +1,327 @@
+package miniflags
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_ReadINI(t *testing.T) {
+	var name, color string
+	var num int
+	var verbose bool
+	var files []string
+	var tests = []struct {
+		ini       string
+		env       map[string]string
+		args      []string
+		wantName  string
+		wantNum   int
+		wantColor string
+		wantFiles []string
+		wantVerb  bool
+		errPrefix string
+	}{
+		{"", nil, []string{}, "", 0, "", nil, false, ""},
+		{"name = bob\nnumber=3\n# comment\n; comment\nverbose\n", nil, []string{}, "bob", 3, "", nil, true, ""},
+		{"name = \"two words\"\nverbose = no\n", nil, []string{}, "two words", 0, "", nil, false, ""},
+		{"name = bob\nnumber=3\n", nil, []string{"--name", "sue"}, "sue", 3, "", nil, false, ""},
+		{"number=3\n", map[string]string{"NUM": "4"}, []string{}, "", 4, "", nil, false, ""},
+		{"[Output options]\ncolor = red\nfile = a\nfile = b\n", nil, []string{}, "", 0, "red", []string{"a", "b"}, false, ""},
+		{"[output-OPTIONS]\ncolor = red\n", nil, []string{}, "", 0, "red", nil, false, ""},
+		{"number = x\n", nil, []string{}, "", 0, "", nil, false, "Error with config key 'number' in test.ini:1: strconv.ParseInt"},
+		{"\nbogus = 1\n", nil, []string{}, "", 0, "", nil, false, "Unknown option 'bogus' in config file test.ini:2"},
+		{"n = 1\n", nil, []string{}, "", 0, "", nil, false, "Unknown option 'n' in config file test.ini:1"},
+		{"[Output options]\nname = bob\n", nil, []string{}, "", 0, "", nil, false, "Unknown option 'name' in config file test.ini:2"},
+		{"[Other]\n", nil, []string{}, "", 0, "", nil, false, "Unknown section '[Other]' in config file test.ini:1"},
+		{"[Other\n", nil, []string{}, "", 0, "", nil, false, "Invalid section line in config file test.ini:1"},
+		{"name\n", nil, []string{}, "", 0, "", nil, false, "Missing value for 'name' in config file test.ini:1"},
+	}
+	for _, test := range tests {
+		name, color, num, verbose, files = "", "", 0, false, nil
+		restore := fakeEnv(test.env)
+		oset := NewOptionSet().
+			Option("name", &name, "").
+			Add(Option("n number", &num, "").Env("NUM")).
+			Option("verbose", &verbose, "").
+			Section("Output options:").
+			Option("color", &color, "").
+			Option("file", &files, "")
+		err := oset.ReadINI(strings.NewReader(test.ini), "test.ini")
+		if err == nil {
+			_, err = oset.ParseArgs(test.args)
+		}
+		restore()
+		if m := checkValErr(t, test.wantName, name, test.errPrefix, err); m != "" {
+			t.Error(test.ini, m)
+		}
+		if m := checkValErr(t, test.wantNum, num, "", nil); m != "" {
+			t.Error(test.ini, m)
+		}
+		if m := checkValErr(t, test.wantColor, color, "", nil); m != "" {
+			t.Error(test.ini, m)
+		}
+		if m := checkValErr(t, test.wantFiles, files, "", nil); m != "" {
+			t.Error(test.ini, m)
+		}
+		if m := checkValErr(t, test.wantVerb, verbose, "", nil); m != "" {
+			t.Error(test.ini, m)
+		}
+	}
+}
+
+func Test_OptionSet_LoadINI(t *testing.T) {
+	var name string
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tool.ini")
+	if err := os.WriteFile(path, []byte("name = bob\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	oset := NewOptionSet().Option("name", &name, "")
+	if err := oset.LoadINI(path); err != nil {
+		t.Fatal(err)
+	}
+	_, err = oset.ParseArgs([]string{})
+	if m := checkValErr(t, "bob", name, "", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, ValueSource{SourceConfig, "name"}, oset.Source("name"), "", nil); m != "" {
+		t.Error(m)
+	}
+	if err := oset.LoadINI(filepath.Join(dir, "missing.ini")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func Test_ConfigFileOption(t *testing.T) {
+	var name, color string
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ini := filepath.Join(dir, "tool.ini")
+	json := filepath.Join(dir, "tool.JSON")
+	if err := os.WriteFile(ini, []byte("name = bob\ncolor = red\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(json, []byte(`{"color": "blue"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var tests = []struct {
+		args      []string
+		wantName  string
+		wantColor string
+		errPrefix string
+	}{
+		{[]string{}, "", "", ""},
+		{[]string{"--config", ini}, "bob", "red", ""},
+		{[]string{"--name=sue", "--config", ini}, "sue", "red", ""},
+		{[]string{"--config", ini, "--name=sue"}, "sue", "red", ""},
+		{[]string{"-c", ini, "-c", json}, "bob", "blue", ""},
+		{[]string{"-c", filepath.Join(dir, "missing.ini")}, "", "", "Error with command line option '-c'"},
+	}
+	for _, test := range tests {
+		name, color = "", ""
+		oset := NewOptionSet().
+			Option("name", &name, "").
+			Option("color", &color, "")
+		oset.Option("c config", ConfigFileOption(oset), "=FILE; Read options from FILE")
+		_, err := oset.ParseArgs(test.args)
+		if m := checkValErr(t, test.wantName, name, test.errPrefix, err); m != "" {
+			t.Error(test.args, m)
+		}
+		if m := checkValErr(t, test.wantColor, color, "", nil); m != "" {
+			t.Error(test.args, m)
+		}
+	}
+
+	// a set parsed again without the option doesn't use the file, but still
+	// uses a file loaded before the parses
+	name, color = "", ""
+	oset := NewOptionSet().
+		Option("name", &name, "").
+		Option("color", &color, "")
+	oset.Option("c config", ConfigFileOption(oset), "=FILE; Read options from FILE")
+	if err := oset.LoadConfig(json); err != nil {
+		t.Fatal(err)
+	}
+	_, err = oset.ParseArgs([]string{"--config", ini})
+	if m := checkValErr(t, []string{"bob", "red"}, []string{name, color}, "", err); m != "" {
+		t.Error(m)
+	}
+	oset.Reset()
+	_, err = oset.ParseArgs([]string{})
+	if m := checkValErr(t, []string{"", "blue"}, []string{name, color}, "", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_ConfigDirs(t *testing.T) {
+	var tests = []struct {
+		env  map[string]string
+		want []string
+	}{
+		{map[string]string{}, []string{"/etc/xdg/tool", "/etc/tool"}},
+		{map[string]string{"HOME": "/home/u"}, []string{"/home/u/.config/tool", "/etc/xdg/tool", "/etc/tool"}},
+		{map[string]string{"HOME": "/home/u", "XDG_CONFIG_HOME": "/cfg", "XDG_CONFIG_DIRS": "/a:rel:/b"},
+			[]string{"/cfg/tool", "/a/tool", "/b/tool", "/etc/tool"}},
+		{map[string]string{"HOME": "/home/u", "XDG_CONFIG_HOME": "rel"}, []string{"/home/u/.config/tool", "/etc/xdg/tool", "/etc/tool"}},
+	}
+	for _, test := range tests {
+		restore := fakeEnv(test.env)
+		got := ConfigDirs("tool")
+		restore()
+		if m := checkValErr(t, test.want, got, "", nil); m != "" {
+			t.Error(test.env, m)
+		}
+	}
+}
+
+func Test_OptionSet_LoadDefaultConfig(t *testing.T) {
+	var name string
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	home, system := filepath.Join(dir, "home"), filepath.Join(dir, "xdg")
+	for _, d := range []string{filepath.Join(home, "tool"), filepath.Join(system, "tool")} {
+		if err := os.MkdirAll(d, 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	systemFile := filepath.Join(system, "tool", "config.json")
+	if err := os.WriteFile(systemFile, []byte(`{"name": "system"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	defer fakeEnv(map[string]string{"XDG_CONFIG_HOME": home, "XDG_CONFIG_DIRS": system})()
+
+	oset := NewOptionSet().Option("name", &name, "")
+	path, err := oset.LoadDefaultConfig("tool")
+	if err == nil {
+		_, err = oset.ParseArgs([]string{})
+	}
+	if m := checkValErr(t, systemFile, path, "", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, "system", name, "", nil); m != "" {
+		t.Error(m)
+	}
+
+	userFile := filepath.Join(home, "tool", "config.ini")
+	if err := os.WriteFile(userFile, []byte("name = user\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	path, err = oset.LoadDefaultConfig("tool")
+	if err == nil {
+		_, err = oset.ParseArgs([]string{})
+	}
+	if m := checkValErr(t, userFile, path, "", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, "user", name, "", nil); m != "" {
+		t.Error(m)
+	}
+
+	path, err = oset.LoadDefaultConfig("tool", "other.ini")
+	if m := checkValErr(t, "", path, "", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_FormatConfigINI(t *testing.T) {
+	num, name, verbose := 3, " x ", false
+	var files []string
+	var color string
+	oset := NewOptionSet().
+		Option("n number", &num, "=NUM; The number\nof things").
+		Add(Option("name", &name, "The name").Required()).
+		Option("v verbose", &verbose, "").
+		Option("x", &num, "").
+		Option("action", func(string) {}, "").
+		Add(Option("secret", &name, "").Hide()).
+		Section("Output options:").
+		Option("color", Choices(&color, []string{"red", "green"}), "=COLOR; Color").
+		Option("file", &files, "A file")
+	want := []string{
+		"# The number",
+		"# of things",
+		"#number = 3",
+		"",
+		"# The name (required)",
+		`#name = " x "`,
+		"",
+		"#verbose = false",
+		"",
+		"[output-options]",
+		"",
+		"# Color (one of: red, green)",
+		"#color =",
+		"",
+		"# A file",
+		"#file =",
+	}
+	if m := checkValErr(t, want, oset.FormatConfigINI(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_LoadConfigURL(t *testing.T) {
+	defer func(saved func(string, string) (io.ReadCloser, string, error)) { FetchConfig = saved }(FetchConfig)
+	var name string
+	oset := NewOptionSet().Option("name", &name, "")
+	FetchConfig = nil
+	err := oset.LoadConfigURL("https://example.com/tool.ini")
+	if m := checkValErr(t, nil, nil, "Cannot fetch config file https://example.com/tool.ini: loading from URLs is not enabled", err); m != "" {
+		t.Error(m)
+	}
+
+	var gotAuth string
+	FetchConfig = func(url, auth string) (io.ReadCloser, string, error) {
+		gotAuth = auth
+		switch url {
+		case "https://example.com/tool.json?v=1":
+			return io.NopCloser(strings.NewReader(`{"name": "json"}`)), "", nil
+		case "https://example.com/config":
+			return io.NopCloser(strings.NewReader(`{"name": "typed"}`)), "application/json", nil
+		}
+		return io.NopCloser(strings.NewReader("name = ini")), "text/plain", nil
+	}
+	var tests = []struct {
+		args []string
+		want []interface{}
+	}{
+		{[]string{"--config", "https://example.com/tool.ini"}, []interface{}{"ini", ""}},
+		{[]string{"--config", "https://example.com/tool.json?v=1"}, []interface{}{"json", ""}},
+		{[]string{"--auth=Bearer xyz", "--config", "HTTP://example.com/config"}, []interface{}{"ini", "Bearer xyz"}},
+		{[]string{"--auth=Bearer xyz", "--config", "https://example.com/config"}, []interface{}{"typed", "Bearer xyz"}},
+		{[]string{"--config", "https://example.com/config", "--auth=Bearer xyz"}, []interface{}{"typed", "Bearer xyz"}},
+	}
+	for _, test := range tests {
+		name, gotAuth = "", ""
+		oset := NewOptionSet().Option("name", &name, "")
+		oset.Option("config", ConfigFileOption(oset), "").
+			Option("auth", ConfigAuthOption(oset), "")
+		_, err := oset.ParseArgs(test.args)
+		if m := checkValErr(t, test.want, []interface{}{name, gotAuth}, "", err); m != "" {
+			t.Error(test.args, m)
+		}
+	}
+
+	// the authorization is not kept for a later parse
+	oset = NewOptionSet().Option("name", &name, "")
+	oset.Option("config", ConfigFileOption(oset), "").
+		Option("auth", ConfigAuthOption(oset), "")
+	oset.ParseArgs([]string{"--auth=Bearer xyz"})
+	_, err = oset.ParseArgs([]string{"--config", "https://example.com/config"})
+	if m := checkValErr(t, "", gotAuth, "", err); m != "" {
+		t.Error(m)
+	}
+}
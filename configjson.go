@@ -0,0 +1,152 @@
+package miniflags
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// LoadJSON reads the JSON config file at path and keeps the values in it for
+// the options of this set. See ReadJSON.
+func (self *OptionSet) LoadJSON(path string) error {
+	return self.keepConfig(self.loadJSON(path))
+}
+
+// Read the JSON config file at path as with readJSON.
+func (self *OptionSet) loadJSON(path string) (*configBatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return self.readJSON(file, path)
+}
+
+// ReadJSON reads a config file in JSON format from r, and keeps the values
+// in it for the options of this set in the same way as ReadINI; name is the
+// file name used in error messages. The file holds an object whose keys are
+// the long names of options, in the format written by ConfigJSON. A value may
+// be a string, number or bool, an array of them for a list option, or null,
+// which is ignored. A key may also name a section, as with ReadINI, with an
+// object value holding the options of that section. Unknown keys are errors
+// unless WarnUnknownConfig is used.
+func (self *OptionSet) ReadJSON(r io.Reader, name string) error {
+	return self.keepConfig(self.readJSON(r, name))
+}
+
+// Read a config file in JSON format as described for ReadJSON. Returns the
+// values and warnings read, and any error.
+func (self *OptionSet) readJSON(r io.Reader, name string) (*configBatch, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	var top map[string]interface{}
+	if err := decoder.Decode(&top); err != nil {
+		return nil, fmt.Errorf("Error in config file %s: %v", name, err)
+	}
+	batch := &configBatch{file: name}
+	return batch, self.readJSONObject(batch, top, "", name)
+}
+
+// Add the values in a JSON object to batch. The section is the config file
+// section name of the object, or "" for the top level.
+func (self *OptionSet) readJSONObject(batch *configBatch, object map[string]interface{}, section, name string) error {
+	keys := []string{}
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		def := self.findConfigKey(section, key)
+		nested, isObject := object[key].(map[string]interface{})
+		switch {
+		case def == nil && isObject && section == "" && self.hasSection(configSectionName(key)):
+			if err := self.readJSONObject(batch, nested, configSectionName(key), name); err != nil {
+				return err
+			}
+			continue
+		case def == nil:
+			if err := self.unknownConfig(batch, fmt.Errorf("Unknown option '%s' in config file %s", key, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		values, err := jsonConfigValues(object[key])
+		if err == nil && len(values) > 1 && !def.isList() {
+			err = fmt.Errorf("expected a single value")
+		}
+		if err != nil {
+			return fmt.Errorf("Error with config key '%s' in %s: %v", key, name, err)
+		}
+		for _, value := range values {
+			batch.add(def, key, name, value)
+		}
+	}
+	return nil
+}
+
+// Convert a decoded JSON value to the strings to set an option with.
+func jsonConfigValues(value interface{}) ([]string, error) {
+	switch value := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{value}, nil
+	case json.Number:
+		return []string{value.String()}, nil
+	case bool:
+		return []string{fmt.Sprint(value)}, nil
+	case []interface{}:
+		out := []string{}
+		for _, item := range value {
+			switch item.(type) {
+			case nil, []interface{}, map[string]interface{}:
+				return nil, fmt.Errorf("invalid list item %v", item)
+			}
+			values, _ := jsonConfigValues(item)
+			out = append(out, values...)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("invalid value %v", value)
+	}
+}
+
+// ConfigJSON returns the current values of the options of this set that
+// have variable targets, as a JSON config file that can be edited and read
+// back with ReadJSON. The keys are the options' first long names, in the
+// order the options were defined; options without a long name are left out.
+func (self *OptionSet) ConfigJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteString("{")
+	sep := "\n"
+	for _, def := range self.list {
+		name := def.canonicalName()
+		if len(name) < 2 || def.hidden {
+			continue
+		}
+		var value interface{}
+		switch target := def.target.(type) {
+		case *string, *uint, *uint64, *int, *int64, *float64, *bool:
+			value = target
+		case *[]string:
+			value = *target
+			if *target == nil {
+				value = []string{}
+			}
+		default:
+			continue
+		}
+		key, _ := json.Marshal(name)
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&out, "%s  %s: %s", sep, key, encoded)
+		sep = ",\n"
+	}
+	out.WriteString("\n}\n")
+	return out.Bytes(), nil
+}
@@ -0,0 +1,127 @@
+package miniflags
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_ReadJSON(t *testing.T) {
+	var name, color string
+	var num int
+	var verbose bool
+	var files []string
+	var tests = []struct {
+		json      string
+		warn      bool
+		wantName  string
+		wantNum   int
+		wantColor string
+		wantFiles []string
+		wantVerb  bool
+		wantEmit  string
+		errPrefix string
+	}{
+		{`{}`, false, "", 0, "", nil, false, "", ""},
+		{`{"name": "bob", "number": 3, "verbose": true, "color": null}`, false, "bob", 3, "", nil, true, "", ""},
+		{`{"number": "4", "file": ["a", "b"]}`, false, "", 4, "", []string{"a", "b"}, false, "", ""},
+		{`{"Output options": {"color": "red"}}`, false, "", 0, "red", nil, false, "", ""},
+		{`{"bogus": 1, "name": "bob"}`, false, "", 0, "", nil, false, "", "Unknown option 'bogus' in config file test.json"},
+		{`{"bogus": 1, "name": "bob"}`, true, "bob", 0, "", nil, false, "Warning: Unknown option 'bogus' in config file test.json", ""},
+		{`{"name": ["a", "b"]}`, false, "", 0, "", nil, false, "", "Error with config key 'name' in test.json: expected a single value"},
+		{`{"name": {"a": 1}}`, false, "", 0, "", nil, false, "", "Error with config key 'name' in test.json: invalid value"},
+		{`{"number": 1.5}`, false, "", 0, "", nil, false, "", "Error with config key 'number' in test.json: strconv.ParseInt"},
+		{`[1]`, false, "", 0, "", nil, false, "", "Error in config file test.json: json: cannot unmarshal"},
+	}
+	saved := Emit
+	defer func() { Emit = saved }()
+	for _, test := range tests {
+		name, color, num, verbose, files = "", "", 0, false, nil
+		emitted := ""
+		Emit = func(a ...interface{}) { emitted += a[0].(string) }
+		oset := NewOptionSet().
+			Option("name", &name, "").
+			Option("n number", &num, "").
+			Option("verbose", &verbose, "").
+			Section("Output options:").
+			Option("color", &color, "").
+			Option("file", &files, "")
+		if test.warn {
+			oset.WarnUnknownConfig()
+		}
+		err := oset.ReadJSON(strings.NewReader(test.json), "test.json")
+		if err == nil {
+			_, err = oset.ParseArgs([]string{})
+		}
+		if m := checkValErr(t, test.wantName, name, test.errPrefix, err); m != "" {
+			t.Error(test.json, m)
+		}
+		if m := checkValErr(t, test.wantNum, num, "", nil); m != "" {
+			t.Error(test.json, m)
+		}
+		if m := checkValErr(t, test.wantColor, color, "", nil); m != "" {
+			t.Error(test.json, m)
+		}
+		if m := checkValErr(t, test.wantFiles, files, "", nil); m != "" {
+			t.Error(test.json, m)
+		}
+		if m := checkValErr(t, test.wantVerb, verbose, "", nil); m != "" {
+			t.Error(test.json, m)
+		}
+		if m := checkValErr(t, test.wantEmit, emitted, "", nil); m != "" {
+			t.Error(test.json, m)
+		}
+	}
+}
+
+func Test_OptionSet_ConfigJSON(t *testing.T) {
+	name, num, verbose, files := "bob", 3, false, []string{"a"}
+	var other []string
+	oset := NewOptionSet().
+		Option("name", &name, "").
+		Option("n number", &num, "").
+		Option("v verbose", &verbose, "").
+		Option("file", &files, "").
+		Option("other", &other, "").
+		Option("x", &num, "").
+		Option("action", func() {}, "")
+	data, err := oset.ConfigJSON()
+	want := "{\n  \"name\": \"bob\",\n  \"number\": 3,\n  \"verbose\": false,\n  \"file\": [\"a\"],\n  \"other\": []\n}\n"
+	if m := checkValErr(t, want, string(data), "", err); m != "" {
+		t.Error(m)
+	}
+
+	name, num, files = "", 0, nil
+	if err := oset.ReadJSON(strings.NewReader(want), "test.json"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = oset.ParseArgs([]string{})
+	if m := checkValErr(t, "bob", name, "", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, []string{"a"}, files, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_WarnUnknownConfig_INI(t *testing.T) {
+	var name string
+	saved := Emit
+	defer func() { Emit = saved }()
+	emitted := []string{}
+	Emit = func(a ...interface{}) { emitted = append(emitted, a[0].(string)) }
+	oset := NewOptionSet().Option("name", &name, "").WarnUnknownConfig()
+	err := oset.ReadINI(strings.NewReader("bogus = 1\n[Other]\nname = sue\n[]\nname = bob\n"), "test.ini")
+	if err == nil {
+		_, err = oset.ParseArgs([]string{})
+	}
+	if m := checkValErr(t, "bob", name, "", err); m != "" {
+		t.Error(m)
+	}
+	want := []string{
+		"Warning: Unknown option 'bogus' in config file test.ini:1",
+		"Warning: Unknown section '[Other]' in config file test.ini:2",
+	}
+	if m := checkValErr(t, want, emitted, "", nil); m != "" {
+		t.Error(m)
+	}
+}
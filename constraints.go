@@ -0,0 +1,262 @@
+package miniflags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A constraint is a rule about the combination of options that may be given,
+// checked after all arguments have been parsed.
+type constraint struct {
+	names []string                              // The option names used by the rule
+	check func(p *parser, names []string) error // Returns an error if the rule is broken in the parse p
+}
+
+// Required marks this option as required: if it is not given on the command
+// line or by another source such as the environment, ParseArgs reports an
+// error such as "Missing required option '--name'". If prompting is enabled
+// with PromptMissing, the user may be asked for the value instead. Required
+// options are marked "(required)" in help output and are listed in the usage
+// header. Returns self so that calls can be chained.
+func (self *OptionDef) Required() *OptionDef {
+	self.required = true
+	return self
+}
+
+// Return the required options of this set as shown in a usage header, e.g.
+// "--name=NAME --force".
+func (self *OptionSet) formatRequiredOptions() string {
+	names := []string{}
+	for _, def := range self.list {
+		if def.required && !def.hidden {
+			valName, _ := def.splitHelp()
+			names = append(names, def.displayName()+valName)
+		}
+	}
+	return strings.Join(names, " ")
+}
+
+// Check that each required option got a value from some source, prompting
+// for missing values if enabled. Returns an error for the first option that
+// is still missing, or for all of them if AllErrors is set.
+func (self *parser) checkRequired() error {
+	var errs Errors
+	for _, def := range self.set.list {
+		if !def.required || self.sources[def].Kind != SourceDefault {
+			continue
+		}
+		if self.set.promptMissing && def.takesParameter() && StdinIsTerminal() {
+			ok, err := self.prompt(def)
+			if err != nil {
+				return err
+			} else if ok {
+				continue
+			}
+		}
+		if self.set.collect(&errs, fmt.Errorf("Missing required option '%s'", def.displayName())) {
+			break
+		}
+	}
+	return errs.err()
+}
+
+// MinCount requires this option to be given at least min times, as with at
+// least one "--input" for a repeatable option. For an option with a
+// *[]string target, the values in the list after parsing are counted, so
+// values from the environment, a config file or the default also count;
+// otherwise the times the option was given on the command line are counted.
+// The count is checked after parsing, and a shortfall is reported as an error
+// such as "Option '--input' must be given at least once". Use MaxCount to
+// limit the count. Returns self so that calls can be chained.
+func (self *OptionDef) MinCount(min int) *OptionDef {
+	self.minCount = min
+	return self
+}
+
+// Check that each option was given at least as many times as its MinCount.
+// Returns an error for the first option that wasn't, or for all of them if
+// AllErrors is set.
+func (self *parser) checkMinCounts() error {
+	var errs Errors
+	for _, def := range self.set.list {
+		if def.minCount <= 0 {
+			continue
+		}
+		count := self.counts[def]
+		if target, ok := def.target.(*[]string); ok {
+			count = len(*target)
+		}
+		var err error
+		switch {
+		case count >= def.minCount:
+			continue
+		case def.minCount == 1:
+			err = fmt.Errorf("Option '%s' must be given at least once", def.displayName())
+		default:
+			err = fmt.Errorf("Option '%s' must be given at least %d times", def.displayName(), def.minCount)
+		}
+		if self.set.collect(&errs, err) {
+			break
+		}
+	}
+	return errs.err()
+}
+
+// RequireTogether declares that the named options (without dashes) form a
+// group: if any of them is given, all of them must be given, as with a user
+// name and password. An option counts as given if it got its value from the
+// command line or from another source such as the environment. The rule is
+// checked after parsing, and a violation is reported as an error naming a
+// missing option. Returns self so that calls can be chained.
+func (self *OptionSet) RequireTogether(names ...string) *OptionSet {
+	return self.addConstraint(append([]string{}, names...), func(p *parser, names []string) error {
+		for _, name := range names {
+			if !p.given(name) {
+				continue
+			}
+			for _, other := range names {
+				if !p.given(other) {
+					return fmt.Errorf("Option '%s' must be given together with '%s'",
+						p.set.lookupDef(other).displayName(), p.set.lookupDef(name).displayName())
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Requires declares that the option called name (without dashes) is only
+// valid when each of the required options is also given, e.g.
+// Requires("tls-cert", "tls-key"). Unlike RequireTogether, the required options
+// may be given on their own. The rule is checked after parsing, and a
+// violation is reported as an error such as "Option '--tls-cert' requires
+// '--tls-key'". Returns self so that calls can be chained.
+func (self *OptionSet) Requires(name string, required ...string) *OptionSet {
+	return self.addConstraint(append([]string{name}, required...), func(p *parser, names []string) error {
+		if !p.given(names[0]) {
+			return nil
+		}
+		for _, other := range names[1:] {
+			if !p.given(other) {
+				return fmt.Errorf("Option '%s' requires '%s'",
+					p.set.lookupDef(names[0]).displayName(), p.set.lookupDef(other).displayName())
+			}
+		}
+		return nil
+	})
+}
+
+// RequiredIf declares that the option called name (without dashes) is
+// required when the option called condition is given, e.g.
+// RequiredIf("key-file", "tls"). A bool condition must also be true, so
+// "--tls=no" doesn't require the key file. If values are listed, the
+// condition option must also have one of them, compared as formatted by
+// fmt.Sprint, e.g. RequiredIf("key-file", "mode", "tls", "mtls"); a list
+// matches if any of its values do. The rule is checked after parsing, and a
+// violation is reported as an error such as "Option '--key-file' is required
+// when '--mode' is tls". Returns self so that calls can be chained.
+func (self *OptionSet) RequiredIf(name, condition string, values ...string) *OptionSet {
+	values = append([]string{}, values...)
+	return self.addConstraint([]string{name, condition}, func(p *parser, names []string) error {
+		def := p.set.lookupDef(names[1])
+		if !p.given(names[1]) || p.given(names[0]) || !p.hasValue(def, values) {
+			return nil
+		}
+		if len(values) == 0 {
+			return fmt.Errorf("Option '%s' is required when '%s' is given",
+				p.set.lookupDef(names[0]).displayName(), def.displayName())
+		}
+		return fmt.Errorf("Option '%s' is required when '%s' is %s",
+			p.set.lookupDef(names[0]).displayName(), def.displayName(), strings.Join(values, " or "))
+	})
+}
+
+// Check whether the option def has one of values in this parse, or, if
+// values is empty, whether it is set: true for a bool target and always for
+// others. A variable target is checked for its value; for other targets,
+// such as Setter values, the last value set in the parse is checked.
+func (self *parser) hasValue(def *OptionDef, values []string) bool {
+	switch target := def.target.(type) {
+	case *bool:
+		if len(values) == 0 {
+			return *target
+		}
+	case *[]string:
+		for _, value := range *target {
+			if containsString(values, value) {
+				return true
+			}
+		}
+		return len(values) == 0
+	}
+	if len(values) == 0 {
+		return true
+	}
+	if value := def.currentValue(); value != nil {
+		return containsString(values, value.(string))
+	}
+	given := self.values[def]
+	return len(given) > 0 && containsString(values, given[len(given)-1])
+}
+
+// Add a constraint that uses the given option names, which are passed to
+// check, so that a translated set can rename them.
+func (self *OptionSet) addConstraint(names []string, check func(p *parser, names []string) error) *OptionSet {
+	self.constraints = append(self.constraints, &constraint{names, check})
+	return self
+}
+
+// Check that all names used by constraints are defined. Returns an error for
+// the first undefined name found.
+func (self *OptionSet) checkConstraintNames() error {
+	for _, c := range self.constraints {
+		for _, name := range c.names {
+			if self.findName(name) == nil {
+				return fmt.Errorf("Unknown option name '%s' used in a constraint", name)
+			}
+		}
+	}
+	return nil
+}
+
+// Check whether the option called name got a value from any source in this
+// parse.
+func (self *parser) given(name string) bool {
+	return self.sources[self.set.findName(name)].Kind != SourceDefault
+}
+
+// Check the constraints of the set against the options given in the parse.
+// Returns an error for the first rule that is broken, or for all of them if
+// AllErrors is set.
+func (self *parser) checkConstraints() error {
+	var errs Errors
+	for _, c := range self.set.constraints {
+		if self.set.collect(&errs, c.check(self, c.names)) {
+			break
+		}
+	}
+	return errs.err()
+}
+
+// Call the functions added with Finally. Returns the first error.
+func (self *OptionSet) runFinally() error {
+	for _, hook := range self.finally {
+		if err := hook(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finally adds a function that is called at the end of each successful
+// parse, after all arguments have been handled, every source of values has
+// been applied, and the other checks have passed, so that it sees the final
+// values of all the targets. It can check relationships between options
+// that the built-in rules don't cover, or derive values from them. An error
+// returned by hook is reported by ParseArgs in the same way as other errors.
+// The functions are called in the order they were added. Returns self so
+// that calls can be chained.
+func (self *OptionSet) Finally(hook func() error) *OptionSet {
+	self.finally = append(self.finally, hook)
+	return self
+}
@@ -0,0 +1,188 @@
+package miniflags
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_OptionSet_RequireTogether(t *testing.T) {
+	defer fakeEnv(map[string]string{"PASSWORD": "secret"})()
+	var user, password, host string
+	var tests = []struct {
+		input     []string
+		errPrefix string
+	}{
+		{[]string{}, ""},
+		{[]string{"-h", "x"}, ""},
+		{[]string{"-u", "me", "-p", "pw"}, ""},
+		{[]string{"-u", "me"}, "Option '--password' must be given together with '--user'"},
+		{[]string{"--password=pw"}, "Option '--user' must be given together with '--password'"},
+	}
+	for _, test := range tests {
+		_, err := NewOptionSet().
+			Option("u user", &user, "").
+			Option("p password", &password, "").
+			Option("h host", &host, "").
+			RequireTogether("user", "password").
+			ParseArgs(test.input)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	// a value from the environment counts as given
+	_, err := NewOptionSet().
+		Option("u user", &user, "").
+		Add(Option("p password", &password, "").Env("PASSWORD")).
+		RequireTogether("user", "password").
+		ParseArgs([]string{"-u", "me"})
+	if m := checkValErr(t, nil, nil, "", err); m != "" {
+		t.Error(m)
+	}
+
+	_, err = NewOptionSet().
+		Option("u user", &user, "").
+		RequireTogether("user", "pass").
+		ParseArgs([]string{})
+	if m := checkValErr(t, nil, nil, "Unknown option name 'pass' used in a constraint", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Requires(t *testing.T) {
+	var cert, key, ca string
+	var tests = []struct {
+		input     []string
+		errPrefix string
+	}{
+		{[]string{}, ""},
+		{[]string{"--tls-key=k"}, ""},
+		{[]string{"--tls-cert=c", "--tls-key=k", "--ca=x"}, ""},
+		{[]string{"--tls-cert=c", "--ca=x"}, "Option '--tls-cert' requires '--tls-key'"},
+		{[]string{"--tls-cert=c", "--tls-key=k"}, "Option '--tls-cert' requires '--ca'"},
+	}
+	for _, test := range tests {
+		_, err := NewOptionSet().
+			Option("tls-cert", &cert, "").
+			Option("tls-key", &key, "").
+			Option("ca", &ca, "").
+			Requires("tls-cert", "tls-key", "ca").
+			ParseArgs(test.input)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionSet_RequiredIf(t *testing.T) {
+	var keyFile, mode, cert, transport string
+	var tls bool
+	var tags []string
+	var tests = []struct {
+		input     []string
+		errPrefix string
+	}{
+		{[]string{}, ""},
+		{[]string{"--tls", "--key-file=k"}, ""},
+		{[]string{"--tls=no"}, ""},
+		{[]string{"--tls"}, "Option '--key-file' is required when '--tls' is given"},
+		{[]string{"--mode=plain"}, ""},
+		{[]string{"--mode=mtls", "--cert=c"}, ""},
+		{[]string{"--mode=mtls"}, "Option '--cert' is required when '--mode' is tls or mtls"},
+		{[]string{"--tag=a", "--tag=secure", "--cert=c"}, ""},
+		{[]string{"--tag=a", "--tag=secure"}, "Option '--cert' is required when '--tag' is secure"},
+		{[]string{"--transport=plain"}, ""},
+		{[]string{"--transport=tls", "--transport=plain"}, ""},
+		{[]string{"--transport=tls"}, "Option '--key-file' is required when '--transport' is tls"},
+	}
+	for _, test := range tests {
+		tls, tags = false, nil
+		_, err := NewOptionSet().
+			Option("tls", &tls, "").
+			Option("key-file", &keyFile, "").
+			Option("mode", &mode, "").
+			Option("cert", &cert, "").
+			Option("tag", &tags, "").
+			Option("transport", Choices(&transport, []string{"plain", "tls"}), "").
+			RequiredIf("key-file", "tls").
+			RequiredIf("key-file", "transport", "tls").
+			RequiredIf("cert", "mode", "tls", "mtls").
+			RequiredIf("cert", "tag", "secure").
+			ParseArgs(test.input)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	_, err := NewOptionSet().Option("tls", &tls, "").RequiredIf("key-file", "tls").ParseArgs([]string{})
+	if m := checkValErr(t, nil, nil, "Unknown option name 'key-file' used in a constraint", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Finally(t *testing.T) {
+	var min, max int
+	var calls []string
+	var tests = []struct {
+		input     []string
+		wantCalls []string
+		errPrefix string
+	}{
+		{[]string{"--min=1", "--max=5"}, []string{"first", "second"}, ""},
+		{[]string{"--min=6", "--max=5"}, []string{"first"}, "--min must not be greater than --max"},
+		{[]string{"--min=x"}, nil, "Error with command line option '--min=x'"},
+		{[]string{"--max=5", "--bogus"}, nil, "Unknown option '--bogus'"},
+	}
+	for _, test := range tests {
+		min, max, calls = 0, 10, nil
+		_, err := NewOptionSet().
+			Option("min", &min, "").
+			Option("max", &max, "").
+			Finally(func() error {
+				calls = append(calls, "first")
+				if min > max {
+					return fmt.Errorf("--min must not be greater than --max")
+				}
+				return nil
+			}).
+			Finally(func() error {
+				calls = append(calls, "second")
+				return nil
+			}).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.wantCalls, calls, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionDef_MinCount(t *testing.T) {
+	var inputs, replicas []string
+	var v bool
+	var tests = []struct {
+		input     []string
+		env       map[string]string
+		errPrefix string
+	}{
+		{[]string{"-i", "a", "-vv", "--replica=1", "--replica=2"}, nil, ""},
+		{[]string{"-i", "a", "-i", "b", "-vv", "--replica=1", "--replica=2"}, nil, ""},
+		{[]string{"-vv", "--replica=1", "--replica=2"}, nil, "Option '--input' must be given at least once"},
+		{[]string{"-vv", "--replica=1", "--replica=2"}, map[string]string{"INPUT": "a"}, ""},
+		{[]string{"-i", "a", "-v", "--replica=1", "--replica=2"}, nil, "Option '--verbose' must be given at least 2 times"},
+		{[]string{"-i", "a", "-vv", "--replica=1"}, nil, "Option '--replica' must be given at least 2 times"},
+		{[]string{"-i", "a", "-vv", "--replica=1", "--replica=2", "--replica=3"}, nil, "Option '--replica' given more than 2 times"},
+	}
+	for _, test := range tests {
+		inputs, replicas = nil, nil
+		restore := fakeEnv(test.env)
+		_, err := NewOptionSet().
+			Add(Option("i input", &inputs, "").Env("INPUT").MinCount(1)).
+			Add(Option("replica", &replicas, "").MinCount(2).MaxCount(2)).
+			Add(Option("v verbose", &v, "").MinCount(2)).
+			ParseArgs(test.input)
+		restore()
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package miniflags
+
+import (
+	"fmt"
+)
+
+// DeprecatedUse reports how often a deprecated option was used.
+type DeprecatedUse struct {
+	Option string // The option's names, formatted as in the help output
+	Note   string // The note given to Deprecated
+	Count  int    // The number of times the option was given
+}
+
+// Deprecated marks this option as deprecated. The note should explain what to
+// use instead, e.g. "use --output". The option still works as before, but each
+// use is reported as a warning, such as "Option '--out' is deprecated: use
+// --output", and counted in the OptionSet's deprecation report. In help
+// output, the note is shown as "(deprecated: use --output)" and the option is
+// moved to the end of its section. Returns self so that calls can be chained.
+func (self *OptionDef) Deprecated(note string) *OptionDef {
+	self.deprecated = note
+	return self
+}
+
+// Count a use of the deprecated option def in the set and report it as a
+// warning.
+func (self *parser) recordDeprecatedUse(def *OptionDef) {
+	if self.set.deprecatedCount == nil {
+		self.set.deprecatedCount = map[*OptionDef]int{}
+	}
+	self.set.deprecatedCount[def]++
+	self.warnQuietly(fmt.Sprintf("Option '%s' is deprecated: %s", def.displayName(), def.deprecated))
+}
+
+// DeprecationReport returns the deprecated options that were given on the
+// command line in all calls to ParseArgs on this set so far, with the number
+// of times each was used, in the order the options were defined. Deprecated
+// options that were never used are not included. Maintainers can use this to
+// decide when an option can be removed.
+func (self *OptionSet) DeprecationReport() []DeprecatedUse {
+	report := []DeprecatedUse{}
+	for _, def := range self.list {
+		if count := self.deprecatedCount[def]; count > 0 {
+			report = append(report, DeprecatedUse{def.formatOptionNames(), def.deprecated, count})
+		}
+	}
+	return report
+}
+
+// FormatDeprecationReport returns the DeprecationReport as lines of text
+// suitable for printing, e.g. with Emit when the program exits.
+func (self *OptionSet) FormatDeprecationReport() []string {
+	out := []string{}
+	for _, use := range self.DeprecationReport() {
+		times := "times"
+		if use.Count == 1 {
+			times = "time"
+		}
+		out = append(out, fmt.Sprintf("%s used %d %s (deprecated: %s)", use.Option, use.Count, times, use.Note))
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package miniflags
+
+import (
+	"testing"
+)
+
+func Test_OptionSet_DeprecationReport(t *testing.T) {
+	var out string
+	var v bool
+	oset := NewOptionSet().
+		Option("o output", &out, "").
+		Add(Option("outfile", &out, "").Deprecated("use --output")).
+		Add(Option("q quiet", &v, "").Deprecated("it does nothing")).
+		Add(Option("old", &v, "").Deprecated("unused"))
+
+	for _, args := range [][]string{{"--outfile=a"}, {"-o", "b", "--outfile", "c", "-q"}, {"--output=d"}} {
+		if _, err := oset.ParseArgs(args); err != nil {
+			t.Error(err)
+		}
+	}
+	want := []DeprecatedUse{
+		{"--outfile", "use --output", 2},
+		{"-q, --quiet", "it does nothing", 1},
+	}
+	if m := checkValErr(t, want, oset.DeprecationReport(), "", nil); m != "" {
+		t.Error(m)
+	}
+	wantLines := []string{
+		"--outfile used 2 times (deprecated: use --output)",
+		"-q, --quiet used 1 time (deprecated: it does nothing)",
+	}
+	if m := checkValErr(t, wantLines, oset.FormatDeprecationReport(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionDef_Deprecated_Help(t *testing.T) {
+	var out string
+	var v bool
+	oset := NewOptionSet().
+		Add(Option("outfile", &out, "=FILE; Output file").Deprecated("use --output")).
+		Option("o output", &out, "=FILE; Output file").
+		Section("Other:").
+		Add(Option("q", &v, "Quiet").Deprecated("it does nothing")).
+		Option("v", &v, "Verbose").
+		Add(Option("h", func() {}, "").Hide())
+	want := []string{
+		"  -o, --output=FILE Output file",
+		"  --outfile=FILE    Output file (deprecated: use --output)",
+		"Other:",
+		"  -v                Verbose",
+		"  -q                Quiet (deprecated: it does nothing)",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,133 @@
+package miniflags
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// OptionDescription is the machine-readable description of an option or
+// argument, as produced by Describe.
+type OptionDescription struct {
+	Names      []string `json:"names"`                // The names, e.g. ["-n", "--number"] or ["SRC..."]
+	Type       string   `json:"type"`                 // The kind of value; see Describe
+	Metavar    string   `json:"metavar,omitempty"`    // The parameter name, e.g. "NUM"
+	Optional   bool     `json:"optional,omitempty"`   // The parameter may be left out; see Implicit
+	Help       string   `json:"help,omitempty"`       // The help text, without a parameter name prefix
+	Default    string   `json:"default,omitempty"`    // The default value, or "" if it is a zero value
+	Required   bool     `json:"required,omitempty"`   // The option must be given; see Required
+	Choices    []string `json:"choices,omitempty"`    // The accepted values, for a target that implements Chooser
+	Env        []string `json:"env,omitempty"`        // Environment variables that supply the value; see Env
+	Section    string   `json:"section,omitempty"`    // The header of the section the option is in
+	Tags       []string `json:"tags,omitempty"`       // Badges attached with Tag
+	Deprecated string   `json:"deprecated,omitempty"` // The note given to Deprecated
+}
+
+// CommandDescription is the machine-readable description of a command, as
+// produced by Describe.
+type CommandDescription struct {
+	Names   []string       `json:"names"`             // The names of the command; the first is canonical
+	Help    string         `json:"help,omitempty"`    // The description of the command
+	Section string         `json:"section,omitempty"` // The header of the section the command is in
+	Default bool           `json:"default,omitempty"` // This is the default command
+	Set     SetDescription `json:"set"`               // The options and arguments of the command
+}
+
+// SetDescription is the machine-readable description of an OptionSet, as
+// produced by Describe.
+type SetDescription struct {
+	Usage       string               `json:"usage"`                 // The usage header line
+	Description string               `json:"description,omitempty"` // The text set with Description
+	Arguments   []OptionDescription  `json:"arguments,omitempty"`   // The positional arguments
+	Options     []OptionDescription  `json:"options,omitempty"`     // The options, in help order
+	Commands    []CommandDescription `json:"commands,omitempty"`    // The commands, with their own options
+}
+
+// Describe returns a structured description of the options, arguments and
+// commands of this set, for tools such as GUIs and web frontends that
+// introspect a program's command line. Hidden options are left out. The Type
+// of each option is one of "flag" for an option without a parameter, "bool",
+// "string", "int", "uint", "float", "list" for a *[]string target, or "value"
+// for a Setter target.
+func (self *OptionSet) Describe() SetDescription {
+	desc := SetDescription{Usage: self.usageHeader(), Description: self.description}
+	for _, def := range self.argumentDefs() {
+		entry := self.describeOption(def, []string{def.positionalName()})
+		entry.Metavar, entry.Optional = "", false
+		desc.Arguments = append(desc.Arguments, entry)
+	}
+	section := ""
+	for _, def := range self.helpList(AutoHelp) {
+		switch {
+		case def.hidden:
+		case def.isSectionHeader():
+			section = strings.TrimSpace(def.help)
+		default:
+			names := strings.Split(def.formatNames(self.isNegatable(def)), ", ")
+			entry := self.describeOption(def, names)
+			entry.Section = section
+			desc.Options = append(desc.Options, entry)
+		}
+	}
+	section = ""
+	for _, cmd := range self.commands {
+		if cmd.isSectionHeader() {
+			section = strings.TrimSpace(cmd.help)
+			continue
+		}
+		desc.Commands = append(desc.Commands, CommandDescription{Names: cmd.names, Help: cmd.help,
+			Section: section, Default: cmd.isDefault, Set: cmd.set.Describe()})
+	}
+	return desc
+}
+
+// DescribeJSON returns the description produced by Describe encoded as
+// indented JSON.
+func (self *OptionSet) DescribeJSON() ([]byte, error) {
+	return json.MarshalIndent(self.Describe(), "", "  ")
+}
+
+// Return the description of an option or argument with the given names.
+func (self *OptionSet) describeOption(def *OptionDef, names []string) OptionDescription {
+	entry := self.helpEntry(def, "", "")
+	valName, _ := def.splitHelp()
+	return OptionDescription{
+		Names:      names,
+		Type:       def.describeType(),
+		Metavar:    strings.TrimPrefix(valName, "="),
+		Optional:   def.optionalParam,
+		Help:       entry.Help,
+		Default:    entry.Default,
+		Required:   entry.Required,
+		Choices:    entry.Choices,
+		Env:        entry.Env,
+		Tags:       entry.Tags,
+		Deprecated: def.deprecated,
+	}
+}
+
+// Return the kind of value the option takes, as described for Describe.
+func (self *OptionDef) describeType() string {
+	switch self.target.(type) {
+	case nil:
+		// the automatic help and version options
+		return "flag"
+	case *bool:
+		return "bool"
+	case *int, *int64:
+		return "int"
+	case *uint, *uint64:
+		return "uint"
+	case *float64:
+		return "float"
+	case *[]string:
+		return "list"
+	case *configFile, *configAuthTarget:
+		return "string"
+	case Setter:
+		return "value"
+	}
+	if self.takesParameter() {
+		return "string"
+	}
+	return "flag"
+}
@@ -0,0 +1,53 @@
+package miniflags
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_OptionSet_Describe(t *testing.T) {
+	var num int
+	var name, src, col string
+	var verbose bool
+	color := Choices(&col, []string{"red", "green"})
+	build := NewOptionSet(Option("O", &num, "=LEVEL; Optimization level"))
+	oset := NewOptionSet().
+		Option("n number", &num, "=NUM; The number").
+		Add(Option("name", &name, "The name").Required().Env("NAME")).
+		Section("Output:").
+		Option("v verbose", &verbose, "Be verbose").
+		Option("color", color, "=COLOR; Color").
+		Add(Option("x", func() {}, "").Hide()).
+		Positional("SRC", &src, "The source").
+		AddCommand(Command("build b", build, nil, "Build it"))
+	got := oset.Describe()
+	wantOptions := []OptionDescription{
+		{Names: []string{"-n", "--number"}, Type: "int", Metavar: "NUM", Help: "The number"},
+		{Names: []string{"--name"}, Type: "string", Help: "The name", Required: true, Env: []string{"NAME"}},
+		{Names: []string{"-v", "--verbose"}, Type: "bool", Help: "Be verbose", Section: "Output:"},
+		{Names: []string{"--color"}, Type: "value", Metavar: "COLOR", Help: "Color", Choices: []string{"red", "green"}, Section: "Output:"},
+		{Names: []string{"-h", "--help"}, Type: "flag", Help: "Print this help message and exit", Section: "Output:"},
+	}
+	if m := checkValErr(t, wantOptions, got.Options, "", nil); m != "" {
+		t.Error(m)
+	}
+	wantArgs := []OptionDescription{{Names: []string{"SRC"}, Type: "string", Help: "The source"}}
+	if m := checkValErr(t, wantArgs, got.Arguments, "", nil); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, []string{"build", "b"}, got.Commands[0].Names, "", nil); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, "LEVEL", got.Commands[0].Set.Options[0].Metavar, "", nil); m != "" {
+		t.Error(m)
+	}
+
+	data, err := oset.DescribeJSON()
+	var decoded SetDescription
+	if err == nil {
+		err = json.Unmarshal(data, &decoded)
+	}
+	if m := checkValErr(t, got.Options, decoded.Options, "", err); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,153 @@
+package miniflags
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Source identifies the kind of place that an option's value came from.
+type Source int
+
+// The possible sources of an option's value.
+const (
+	SourceDefault     Source = iota // The target's initial value was not changed
+	SourceEnv                       // An environment variable
+	SourceCommandLine               // An option on the command line
+	SourcePrompt                    // The user's answer to a prompt
+	SourceConfig                    // A config file
+	SourceRemembered                // A value saved by RememberValues
+)
+
+// String returns a short lower-case description of the source.
+func (self Source) String() string {
+	switch self {
+	case SourceDefault:
+		return "default"
+	case SourceEnv:
+		return "environment"
+	case SourceCommandLine:
+		return "command line"
+	case SourcePrompt:
+		return "prompt"
+	case SourceConfig:
+		return "config file"
+	case SourceRemembered:
+		return "remembered value"
+	default:
+		return fmt.Sprintf("Source(%d)", int(self))
+	}
+}
+
+// ValueSource records where an option got its value during a parse.
+type ValueSource struct {
+	Kind Source // The kind of source
+	Key  string // The environment variable name, command line option or config key used
+}
+
+// LookupEnv is called to look up environment variables for options. The
+// default is os.LookupEnv. This function can be replaced by the client, for
+// example to supply a fixed environment in tests.
+var LookupEnv = os.LookupEnv
+
+// Env declares environment variables that supply a value for this option when
+// it is not given on the command line. The variables are checked in the given
+// order and the first one that is set is used, so a renamed variable can be
+// listed before its legacy name during a migration. The variable's value is
+// handled as if it were the option's parameter, except that a bool target is
+// set to the parsed value, as with "--flag=false". The variables are listed
+// at the end of the option's help text, as in "[env: MYTOOL_PORT]". Returns
+// self so that calls can be chained.
+func (self *OptionDef) Env(keys ...string) *OptionDef {
+	self.envKeys = append(self.envKeys, keys...)
+	return self
+}
+
+// EnvPrefix binds every option in this set that has a long name and a
+// variable or Setter target to an environment variable named after it, as if
+// Env had been called for each one. Options with function targets are left
+// out, so that a variable can't run an action such as "--wipe". The name is the prefix and the option's first long name joined by an
+// underscore, in upper case and with dashes changed to underscores, so that
+// with EnvPrefix("MYTOOL") the option "--dry-run" is bound to MYTOOL_DRY_RUN.
+// This applies to options added both before and after the call. Variables
+// declared with Env are checked first. Returns self so that calls can be
+// chained.
+func (self *OptionSet) EnvPrefix(prefix string) *OptionSet {
+	self.envPrefix = prefix
+	for _, def := range self.list {
+		def.envPrefix = prefix
+	}
+	return self
+}
+
+// Return the environment variables that may supply a value for this option:
+// those declared with Env, followed by any derived from its name for
+// EnvPrefix.
+func (self *OptionDef) allEnvKeys() []string {
+	name := self.canonicalName()
+	if self.envPrefix == "" || len(name) < 2 || !self.takesEnvValue() {
+		return self.envKeys
+	}
+	key := self.envPrefix + "_" + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+	return append(self.envKeys[:len(self.envKeys):len(self.envKeys)], key)
+}
+
+// Check whether the target of this option holds a value that a variable
+// derived for EnvPrefix can supply: a variable or a Setter, other than a
+// config file or authorization named on the command line.
+func (self *OptionDef) takesEnvValue() bool {
+	switch self.target.(type) {
+	case *configFile, *configAuthTarget:
+		return false
+	case *string, *uint, *uint64, *int, *int64, *float64, *bool, *[]string, Setter:
+		return true
+	}
+	return false
+}
+
+// DefaultFromEnv sets the default value of this option from the environment
+// variable key, if it is set. The variable is read immediately, when the
+// option is being defined, and its value is converted as with Env and stored
+// in the target; if the variable is not set, the target's value is left as
+// the default. The help text for the option notes the variable name. A
+// conversion error is reported when ParseArgs is called. Returns self so that
+// calls can be chained.
+func (self *OptionDef) DefaultFromEnv(key string) *OptionDef {
+	self.defaultEnv = key
+	if value, ok := LookupEnv(key); ok {
+		if err := self.setExplicit(value, nil); err != nil && self.setupError == nil {
+			self.setupError = &ErrBadValue{Option: self.displayName(), Value: value, Source: ValueSource{SourceEnv, key}, Err: err}
+		}
+		self.envDefaulted = true
+	}
+	return self
+}
+
+// Source returns where the option with the given name got its value during
+// the most recent call to ParseArgs. The Kind is SourceDefault if the option
+// was not set, or if there is no such option.
+func (self *OptionSet) Source(name string) ValueSource {
+	return self.sources[self.lookupDef(name)]
+}
+
+// Look up the environment variables of def and set the first one found. If
+// given is true, the option was given on the command line, and its target is
+// reset to the default first. Returns true if a variable was found, and any
+// error from converting the value.
+func (self *parser) applyEnv(def *OptionDef, given bool) (bool, error) {
+	for _, key := range def.allEnvKeys() {
+		value, ok := LookupEnv(key)
+		if !ok {
+			continue
+		}
+		if given {
+			def.restoreDefault()
+		}
+		if err := def.setExplicit(value, self); err != nil {
+			return false, &ErrBadValue{Option: def.displayName(), Value: value, Source: ValueSource{SourceEnv, key}, Err: err}
+		}
+		self.sources[def] = ValueSource{SourceEnv, key}
+		return true, nil
+	}
+	return false, nil
+}
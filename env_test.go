@@ -0,0 +1,174 @@
+package miniflags
+
+import (
+	"testing"
+)
+
+// install a fake environment for LookupEnv; returns a function restoring it
+func fakeEnv(env map[string]string) func() {
+	saved := LookupEnv
+	LookupEnv = func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	return func() { LookupEnv = saved }
+}
+
+func Test_OptionDef_Env(t *testing.T) {
+	var token string
+	var n int
+	var b bool
+	var tests = []struct {
+		env       map[string]string
+		input     []string
+		wantToken string
+		wantN     int
+		wantB     bool
+		wantSrc   ValueSource
+		errPrefix string
+	}{
+		{map[string]string{}, []string{}, "", 0, false, ValueSource{}, ""},
+		{map[string]string{"OLD_TOKEN": "old"}, []string{}, "old", 0, false, ValueSource{SourceEnv, "OLD_TOKEN"}, ""},
+		{map[string]string{"OLD_TOKEN": "old", "NEW_TOKEN": "new"}, []string{}, "new", 0, false, ValueSource{SourceEnv, "NEW_TOKEN"}, ""},
+		{map[string]string{"NEW_TOKEN": "new"}, []string{"--token=cli"}, "cli", 0, false, ValueSource{SourceCommandLine, "--token=cli"}, ""},
+		{map[string]string{"N": "4", "B": "1"}, []string{}, "", 4, true, ValueSource{}, ""},
+		{map[string]string{"N": "x"}, []string{}, "", 0, false, ValueSource{}, "Error with environment variable 'N': strconv.ParseInt"},
+	}
+	for _, test := range tests {
+		token, n, b = "", 0, false
+		restore := fakeEnv(test.env)
+		oset := NewOptionSet().
+			Add(Option("token", &token, "").Env("NEW_TOKEN", "OLD_TOKEN")).
+			Add(Option("n", &n, "").Env("N")).
+			Add(Option("b", &b, "").Env("B"))
+		_, err := oset.ParseArgs(test.input)
+		restore()
+		if m := checkValErr(t, test.wantToken, token, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantN, n, "", nil); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantB, b, "", nil); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantSrc, oset.Source("token"), "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionDef_DefaultFromEnv(t *testing.T) {
+	defer func() { AutoHelp = true }()
+	AutoHelp = false
+	defer fakeEnv(map[string]string{"PORT": "9000", "BAD": "x"})()
+
+	port := 8080
+	host := "localhost"
+	oset := NewOptionSet().
+		Add(Option("p port", &port, "=PORT; Listen port").DefaultFromEnv("PORT")).
+		Add(Option("host", &host, "=HOST; Listen host").DefaultFromEnv("HOST"))
+	if port != 9000 || host != "localhost" {
+		t.Errorf("Got defaults %d, '%s'; expected 9000, 'localhost'", port, host)
+	}
+	want := []string{
+		"  -p, --port=PORT   Listen port (default from $PORT)",
+		"  --host=HOST       Listen host (default from $HOST)",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+
+	_, err := oset.ParseArgs([]string{})
+	if m := checkValErr(t, ValueSource{SourceEnv, "PORT"}, oset.Source("port"), "", err); m != "" {
+		t.Error(m)
+	}
+	_, err = oset.ParseArgs([]string{"-p", "1"})
+	if m := checkValErr(t, 1, port, "", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, SourceCommandLine, oset.Source("port").Kind, "", nil); m != "" {
+		t.Error(m)
+	}
+
+	var n int
+	_, err = NewOptionSet().
+		Add(Option("n", &n, "").DefaultFromEnv("BAD")).
+		ParseArgs([]string{})
+	if m := checkValErr(t, nil, nil, "Error with environment variable 'BAD'", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionDef_Env_Help(t *testing.T) {
+	port := 80
+	var host string
+	oset := NewOptionSet().
+		Add(Option("p port", &port, "=PORT; Listen port").Env("MYTOOL_PORT")).
+		Add(Option("host", &host, "=HOST; Listen host").Env("MYTOOL_HOST", "HOST")).
+		Add(Option("h", func() {}, "").Hide()).
+		ShowDefaults()
+	want := []string{
+		"  -p, --port=PORT   Listen port (default: 80) [env: MYTOOL_PORT]",
+		"  --host=HOST       Listen host [env: MYTOOL_HOST, HOST]",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_EnvPrefix(t *testing.T) {
+	var number int
+	var dryRun, v, wiped bool
+	var color string
+	var tests = []struct {
+		env       map[string]string
+		input     []string
+		wantN     int
+		wantDry   bool
+		wantColor string
+		errPrefix string
+	}{
+		{map[string]string{}, []string{}, 0, false, "", ""},
+		{map[string]string{"MYTOOL_NUMBER": "3", "MYTOOL_DRY_RUN": "true"}, []string{}, 3, true, "", ""},
+		{map[string]string{"MYTOOL_NUMBER": "3"}, []string{"-n", "4"}, 4, false, "", ""},
+		{map[string]string{"MYTOOL_COLOR": "red", "COLOR": "blue"}, []string{}, 0, false, "blue", ""},
+		{map[string]string{"MYTOOL_COLOR": "red"}, []string{}, 0, false, "red", ""},
+		{map[string]string{"MYTOOL_V": "1"}, []string{}, 0, false, "", ""},
+		{map[string]string{"MYTOOL_WIPE": "no"}, []string{}, 0, false, "", ""},
+		{map[string]string{"MYTOOL_NUMBER": "x"}, []string{}, 0, false, "", "Error with environment variable 'MYTOOL_NUMBER'"},
+	}
+	for _, test := range tests {
+		number, dryRun, v, wiped, color = 0, false, false, false, ""
+		restore := fakeEnv(test.env)
+		_, err := NewOptionSet().
+			Option("n number", &number, "").
+			EnvPrefix("MYTOOL").
+			Option("dry-run", &dryRun, "").
+			Option("v", &v, "").
+			Option("wipe", func() { wiped = true }, "").
+			Add(Option("color", &color, "").Env("COLOR")).
+			ParseArgs(test.input)
+		restore()
+		if m := checkValErr(t, test.wantN, number, test.errPrefix, err); m != "" {
+			t.Error(test.env, m)
+		}
+		if m := checkValErr(t, test.wantDry, dryRun, "", nil); m != "" {
+			t.Error(test.env, m)
+		}
+		if m := checkValErr(t, test.wantColor, color, "", nil); m != "" {
+			t.Error(test.env, m)
+		}
+		if m := checkValErr(t, []bool{false, false}, []bool{v, wiped}, "", nil); m != "" {
+			t.Error(test.env, m)
+		}
+	}
+
+	oset := NewOptionSet().EnvPrefix("MYTOOL").
+		Option("n number", &number, "=NUM; The number").
+		Add(Option("h", func() {}, "").Hide())
+	want := []string{"  -n, --number=NUM  The number [env: MYTOOL_NUMBER]"}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,221 @@
+package miniflags
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors is the error returned by ParseArgs when AllErrors is set and more
+// than one problem is found. Its message has the message of each error on a
+// separate line, in the order they were found.
+type Errors []error
+
+// Error returns the messages of the errors, one per line.
+func (self Errors) Error() string {
+	lines := []string{}
+	for _, err := range self {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Return the collected errors as a single error: nil if there are none, the
+// error itself if there is one, or else the whole list.
+func (self Errors) err() error {
+	switch len(self) {
+	case 0:
+		return nil
+	case 1:
+		return self[0]
+	}
+	return self
+}
+
+// AllErrors makes ParseArgs collect the problems it finds and report them
+// together in one message, one per line, so that a user can fix the command
+// line in one pass. Invalid option values, missing required options and
+// arguments, and broken constraints are all collected; the other checks are
+// still made after an invalid value, but the Finally functions are only
+// called if everything else passed. Problems that stop the parse, such as an
+// unknown option or a missing parameter, are reported along with any found
+// before them. If more than one problem is found, the error is an Errors
+// value. Returns self so that calls can be chained.
+func (self *OptionSet) AllErrors() *OptionSet {
+	self.allErrors = true
+	return self
+}
+
+// Add err, or the errors in it if it is an Errors value, to errs. Returns
+// true if checking should stop: err is not nil and this set doesn't collect
+// all errors.
+func (self *OptionSet) collect(errs *Errors, err error) bool {
+	if list, ok := err.(Errors); ok {
+		*errs = append(*errs, list...)
+	} else if err != nil {
+		*errs = append(*errs, err)
+	}
+	return err != nil && !self.allErrors
+}
+
+// ErrHelp is returned by ParseArgs, Execute and the other parsing methods
+// when the automatic help option or help command was given. The usage
+// message has already been displayed.
+var ErrHelp = errors.New("Help requested")
+
+// ErrVersion is returned by ParseArgs, Execute and the other parsing methods
+// when the automatic version option was given. The version has already been
+// displayed.
+var ErrVersion = errors.New("Version requested")
+
+// ErrorExitStatus is the exit status used by the default OnError function.
+// It can be changed to follow another convention, such as 2 for usage errors
+// as in BSD tools, or 64 for EX_USAGE.
+var ErrorExitStatus = 1
+
+// HelpExitStatus is the exit status returned by ExitStatus for ErrHelp and
+// ErrVersion.
+var HelpExitStatus = 0
+
+// ExitStatus returns the status that a program should exit with after
+// ParseArgs or Run returned err: 0 if err is nil, HelpExitStatus for ErrHelp
+// or ErrVersion, and ErrorExitStatus otherwise, as in:
+//
+//	if _, err := set.ParseArgs(nil); err != nil {
+//		os.Exit(miniflags.ExitStatus(err))
+//	}
+func ExitStatus(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrHelp) || errors.Is(err, ErrVersion):
+		return HelpExitStatus
+	}
+	return ErrorExitStatus
+}
+
+// ErrorHandling defines how an OptionSet handles a parse error; see the
+// ErrorHandling method.
+type ErrorHandling int
+
+const (
+	ExitOnError     ErrorHandling = iota // Call OnError, which by default exits the program
+	ContinueOnError                      // Return the error
+	PanicOnError                         // Panic with the error
+)
+
+// ErrorHandling sets how parse errors in this set are handled, so that a
+// library embedding miniflags can get errors back instead of having the
+// process exit. With the default, ExitOnError, errors are passed to OnError,
+// which displays the usage message and the error and exits the program. With
+// ContinueOnError, ParseArgs and its variants return errors without calling
+// OnError. PanicOnError is the same, except that an error other than ErrHelp
+// or ErrVersion causes a panic with the error as its value. The setting
+// applies to the commands of this set too. Returns self so that calls can be
+// chained.
+func (self *OptionSet) ErrorHandling(mode ErrorHandling) *OptionSet {
+	self.errorHandling = mode
+	return self
+}
+
+// Handle an error found outside of a parse according to the ErrorHandling
+// of this set.
+func (self *OptionSet) handleError(err error) {
+	switch self.errorHandling {
+	case ExitOnError:
+		self.onErrorFunc(err)
+	case PanicOnError:
+		panic(err)
+	}
+}
+
+// Panic with the error *err, unless it is nil or only reports that help or
+// the version was shown; deferred by parse for PanicOnError.
+func (self *OptionSet) panicOnError(err *error) {
+	if *err != nil && *err != ErrHelp && *err != ErrVersion {
+		panic(*err)
+	}
+}
+
+// Unwrap returns the errors, so that errors.Is and errors.As look at each of
+// them.
+func (self Errors) Unwrap() []error {
+	return self
+}
+
+// ErrUnknownOption is the error for an option on the command line that is
+// not defined, such as "Unknown option '--bogus'".
+type ErrUnknownOption struct {
+	Name string // The option as given, e.g. "--bogus" or "-x"
+}
+
+func (self *ErrUnknownOption) Error() string {
+	return fmt.Sprintf("Unknown option '%s'", self.Name)
+}
+
+// ErrMissingParameter is the error for an option that takes a parameter but
+// was given as the last argument without one, such as "Expected a parameter
+// after option '--output'".
+type ErrMissingParameter struct {
+	Option string // The option as given, e.g. "--output" or "-o"
+}
+
+func (self *ErrMissingParameter) Error() string {
+	return fmt.Sprintf("Expected a parameter after option '%s'", self.Option)
+}
+
+// ErrBadValue is the error for a value of an option or positional argument
+// that could not be converted to the type of its target, or was rejected by
+// a validator or setter, such as "Error with command line option
+// '--percent=200': value 200 for --percent must be between 0 and 100". Err
+// is the underlying error, which errors.Is and errors.As also examine.
+type ErrBadValue struct {
+	Option string      // The name as shown in messages, e.g. "--percent" or "SRC"
+	Value  string      // The value that was rejected
+	Source ValueSource // Where the value came from, with the argument, variable or key as given
+	Err    error       // The conversion or validation error
+
+	where      string // The location in a config file, for a config value
+	positional bool   // The value is for a positional argument
+}
+
+func (self *ErrBadValue) Error() string {
+	switch {
+	case self.positional:
+		return fmt.Sprintf("Error with argument %s '%s': %v", self.Option, self.Value, self.Err)
+	case self.Source.Kind == SourceEnv:
+		return fmt.Sprintf("Error with environment variable '%s': %v", self.Source.Key, self.Err)
+	case self.Source.Kind == SourceConfig:
+		return fmt.Sprintf("Error with config key '%s' in %s: %v", self.Source.Key, self.where, self.Err)
+	case self.Source.Kind == SourceRemembered:
+		return fmt.Sprintf("Error with remembered value of '%s' in %s: %v", self.Source.Key, self.where, self.Err)
+	}
+	return fmt.Sprintf("Error with command line option '%s': %v", self.Source.Key, self.Err)
+}
+
+// Unwrap returns the underlying error.
+func (self *ErrBadValue) Unwrap() error {
+	return self.Err
+}
+
+// ParseError is the error for a problem with a particular argument on the
+// command line, such as an unknown option or an invalid value. Index and
+// Token identify the argument, so that a wrapper or editor can highlight it;
+// the message is that of Err, which is usually an ErrUnknownOption,
+// ErrMissingParameter or ErrBadValue that errors.As can find. Problems that
+// aren't tied to one argument, such as a missing required option, are not
+// reported as a ParseError.
+type ParseError struct {
+	Index int    // The index of the argument in the list being parsed, after any response files are expanded
+	Token string // The argument as given, e.g. "-vx" or "--count=x"
+	Err   error  // The underlying error
+}
+
+func (self *ParseError) Error() string {
+	return self.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (self *ParseError) Unwrap() error {
+	return self.Err
+}
@@ -0,0 +1,261 @@
+package miniflags
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func Test_OptionSet_AllErrors(t *testing.T) {
+	var percent int
+	var name, user, password string
+	var inputs []string
+	var tests = []struct {
+		input     []string
+		count     int
+		errPrefix string
+	}{
+		{[]string{"--name=n", "-i", "a"}, 0, ""},
+		{[]string{"--percent=200", "-i", "a"}, 2, "Error with command line option '--percent=200': value 200 for --percent must be between 0 and 100\n" +
+			"Missing required option '--name'"},
+		{[]string{"--percent=x", "--percent=200", "--user=u"}, 5, "Error with command line option '--percent=x': strconv.ParseInt: parsing \"x\": invalid syntax\n" +
+			"Error with command line option '--percent=200': value 200 for --percent must be between 0 and 100\n" +
+			"Missing required option '--name'\n" +
+			"Option '--input' must be given at least once\n" +
+			"Option '--password' must be given together with '--user'"},
+		{[]string{"--percent=200", "--bogus", "--name=n"}, 2, "Error with command line option '--percent=200': value 200 for --percent must be between 0 and 100\n" +
+			"Unknown option '--bogus'"},
+		{[]string{"--name=n"}, 1, "Option '--input' must be given at least once"},
+	}
+	for _, test := range tests {
+		inputs = nil
+		_, err := NewOptionSet().
+			Add(Option("percent", &percent, "").Range(0, 100)).
+			Add(Option("name", &name, "").Required()).
+			Add(Option("i input", &inputs, "").MinCount(1)).
+			Option("user", &user, "").
+			Option("password", &password, "").
+			RequireTogether("user", "password").
+			AllErrors().
+			ParseArgs(test.input)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if list, ok := err.(Errors); ok && len(list) != test.count || !ok && test.count > 1 {
+			t.Errorf("%v: expected %d errors, got %#v", test.input, test.count, err)
+		}
+	}
+
+	// a bad value for a required option is not also reported as missing
+	_, err := NewOptionSet().
+		Add(Option("percent", &percent, "").Range(0, 100).Required()).
+		AllErrors().
+		ParseArgs([]string{"--percent=200"})
+	if _, ok := err.(*ParseError); !ok {
+		t.Errorf("expected a single error, got %#v", err)
+	}
+
+	// without AllErrors, only the first problem is reported
+	_, err = NewOptionSet().
+		Add(Option("percent", &percent, "").Range(0, 100)).
+		Add(Option("name", &name, "").Required()).
+		ParseArgs([]string{"--percent=200"})
+	if m := checkValErr(t, nil, nil, "Error with command line option '--percent=200': value 200 for --percent must be between 0 and 100", err); m != "" {
+		t.Error(m)
+	} else if err.Error() != "Error with command line option '--percent=200': value 200 for --percent must be between 0 and 100" {
+		t.Errorf("got error %q", err)
+	}
+}
+
+func Test_OptionSet_ErrorHandling(t *testing.T) {
+	savedOnError, savedEmit := OnError, Emit
+	defer func() { OnError, Emit = savedOnError, savedEmit }()
+	reported := 0
+	OnError = func(*OptionSet, ...interface{}) { reported++ }
+	Emit = func(...interface{}) {}
+
+	var n int
+	var tests = []struct {
+		handling     ErrorHandling
+		input        []string
+		wantReported int
+		wantPanic    bool
+		errPrefix    string
+	}{
+		{ExitOnError, []string{"-n", "1", "sub"}, 0, false, ""},
+		{ExitOnError, []string{"-n", "x"}, 1, false, "Error with command line option '-n'"},
+		{ExitOnError, []string{"--help"}, 0, false, "Help requested"},
+		{ExitOnError, []string{"help", "sub"}, 0, false, "Help requested"},
+		{ExitOnError, []string{"sub", "-h"}, 0, false, "Help requested"},
+		{ContinueOnError, []string{"-n", "x"}, 0, false, "Error with command line option '-n'"},
+		{ContinueOnError, []string{"--help"}, 0, false, "Help requested"},
+		{ContinueOnError, []string{"sub", "--bogus"}, 0, false, "Unknown option '--bogus'"},
+		{PanicOnError, []string{"-n", "1", "sub"}, 0, false, ""},
+		{PanicOnError, []string{"--help"}, 0, false, "Help requested"},
+		{PanicOnError, []string{"-n", "x"}, 0, true, "Error with command line option '-n'"},
+		{PanicOnError, []string{"sub", "--bogus"}, 0, true, "Unknown option '--bogus'"},
+	}
+	for _, test := range tests {
+		reported = 0
+		set := NewOptionSet().Option("n", &n, "").
+			AddCommand(Command("sub", NewOptionSet(), func([]string) error { return nil }, "")).
+			ErrorHandling(test.handling)
+		var err error
+		panicked := func() (panicked bool) {
+			defer func() {
+				if r := recover(); r != nil {
+					panicked = true
+					err, _ = r.(error)
+				}
+			}()
+			_, err = set.ParseArgs(test.input)
+			return false
+		}()
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if panicked != test.wantPanic || reported != test.wantReported {
+			t.Errorf("%v: got panic %v and %d OnError calls, expected %v and %d",
+				test.input, panicked, reported, test.wantPanic, test.wantReported)
+		}
+	}
+
+	set := NewOptionSet().Option("n", &n, "").ErrorHandling(ContinueOnError)
+	if err := set.ParseOnly([]string{}, "bogus"); err == nil || reported != 0 {
+		t.Errorf("ParseOnly: got error %v and %d OnError calls", err, reported)
+	}
+}
+
+func Test_ErrorTypes(t *testing.T) {
+	var n int
+	var src string
+	var tests = []struct {
+		input []string
+		env   map[string]string
+		check func(err error) bool
+	}{
+		{[]string{"--bogus"}, nil, func(err error) bool {
+			var e *ErrUnknownOption
+			return errors.As(err, &e) && e.Name == "--bogus"
+		}},
+		{[]string{"-n"}, nil, func(err error) bool {
+			var e *ErrMissingParameter
+			return errors.As(err, &e) && e.Option == "-n"
+		}},
+		{[]string{"--num=x"}, nil, func(err error) bool {
+			var e *ErrBadValue
+			return errors.As(err, &e) && e.Option == "--num" && e.Value == "x" &&
+				e.Source == ValueSource{SourceCommandLine, "--num=x"} && errors.Is(err, strconv.ErrSyntax)
+		}},
+		{[]string{"s"}, map[string]string{"NUM": "200"}, func(err error) bool {
+			var e *ErrBadValue
+			return errors.As(err, &e) && e.Value == "200" && e.Source == ValueSource{SourceEnv, "NUM"}
+		}},
+		{[]string{"--num=1"}, nil, func(err error) bool {
+			var e *ErrBadValue
+			return err != nil && !errors.As(err, &e)
+		}},
+	}
+	for _, test := range tests {
+		restore := fakeEnv(test.env)
+		_, err := NewOptionSet().
+			Add(Option("n num", &n, "").Env("NUM").Range(0, 100)).
+			Add(Positional("SRC", &src, "")).
+			ParseArgs(test.input)
+		restore()
+		if !test.check(err) {
+			t.Errorf("%v: unexpected error %#v", test.input, err)
+		}
+	}
+
+	_, err := NewOptionSet().Add(Option("num", &n, "").Range(0, 100)).AllErrors().
+		ParseArgs([]string{"--num=x", "--num=200", "--bogus"})
+	var unknown *ErrUnknownOption
+	if !errors.As(err, &unknown) || !errors.Is(err, strconv.ErrSyntax) {
+		t.Errorf("unexpected error %#v", err)
+	}
+}
+
+func Test_ParseError(t *testing.T) {
+	var n int
+	var v bool
+	var tests = []struct {
+		input     []string
+		wantIndex int
+		wantToken string
+		errPrefix string
+	}{
+		{[]string{"-v", "--bogus"}, 1, "--bogus", "Unknown option '--bogus'"},
+		{[]string{"-v", "-n", "x"}, 2, "x", "Error with command line option '-n': strconv.ParseInt"},
+		{[]string{"-vn=x"}, 0, "-vn=x", "Error with command line option '-n=x'"},
+		{[]string{"-v", "-n"}, 1, "-n", "Expected a parameter after option '-n'"},
+		{[]string{"-v", "sub", "a", "--bad"}, 3, "--bad", "Unknown option '--bad'"},
+		{[]string{"-v", "bus"}, 1, "bus", "Unknown command 'bus'"},
+	}
+	for _, test := range tests {
+		_, err := NewOptionSet().
+			Option("n", &n, "").
+			Option("v", &v, "").
+			AddCommand(Command("sub", NewOptionSet(), func([]string) error { return nil }, "")).
+			ParseArgs(test.input)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		var perr *ParseError
+		if !errors.As(err, &perr) || perr.Index != test.wantIndex || perr.Token != test.wantToken {
+			t.Errorf("%v: expected a ParseError at %d %q, got %#v", test.input, test.wantIndex, test.wantToken, err)
+		}
+	}
+
+	_, err := NewOptionSet().Add(Option("n", &n, "").Required()).ParseArgs([]string{})
+	var perr *ParseError
+	if err == nil || errors.As(err, &perr) {
+		t.Errorf("expected a plain error, got %#v", err)
+	}
+}
+
+func Test_ExitStatus(t *testing.T) {
+	defer func(e, h int) { ErrorExitStatus, HelpExitStatus = e, h }(ErrorExitStatus, HelpExitStatus)
+	var tests = []struct {
+		err  error
+		want []int
+	}{
+		{nil, []int{0, 0}},
+		{ErrHelp, []int{0, 3}},
+		{ErrVersion, []int{0, 3}},
+		{&ParseError{Err: ErrHelp}, []int{0, 3}},
+		{&ErrUnknownOption{Name: "-x"}, []int{1, 64}},
+	}
+	for _, test := range tests {
+		ErrorExitStatus, HelpExitStatus = 1, 0
+		got := []int{ExitStatus(test.err)}
+		ErrorExitStatus, HelpExitStatus = 64, 3
+		got = append(got, ExitStatus(test.err))
+		if m := checkValErr(t, test.want, got, "", nil); m != "" {
+			t.Error(test.err, m)
+		}
+	}
+
+	// with AllErrors, problems found before the help option still fail
+	ErrorExitStatus, HelpExitStatus = 1, 0
+	var n int
+	var parseTests = []struct {
+		input []string
+		want  int
+	}{
+		{[]string{"--help"}, 0},
+		{[]string{"-n", "x", "--help"}, 1},
+		{[]string{"-n", "x", "-m", "--help"}, 1},
+	}
+	for _, test := range parseTests {
+		_, err := NewOptionSet().
+			Option("n", &n, "").
+			AllErrors().
+			UsageFunc(func(*OptionSet) {}).
+			ErrorHandling(ContinueOnError).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, ExitStatus(err), "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package miniflags
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// An example command line shown in help output
+type example struct {
+	cmdline     string // The example arguments, without the program name
+	description string // What the example does
+}
+
+// Example adds an example command line to the help output of this set. The
+// examples are shown in an "Examples:" block after the options, in the order
+// they were added, each with the program name (and any command names)
+// followed by cmdline, and the description on the following line. Returns
+// self so that calls can be chained.
+func (self *OptionSet) Example(cmdline, description string) *OptionSet {
+	self.examples = append(self.examples, example{cmdline, description})
+	return self
+}
+
+// Description sets a few sentences about what the program or command does,
+// which the usage message shows between the usage header and the lists of
+// arguments and options, separated from them by blank lines. The text may
+// contain several lines. Returns self so that calls can be chained.
+func (self *OptionSet) Description(text string) *OptionSet {
+	self.description = strings.TrimRight(text, "\n")
+	return self
+}
+
+// Epilog sets free-form text, such as a documentation URL or an address for
+// bug reports, that the usage message shows after everything else, separated
+// by a blank line. The text may contain several lines. Returns self so that
+// calls can be chained.
+func (self *OptionSet) Epilog(text string) *OptionSet {
+	self.epilog = strings.TrimRight(text, "\n")
+	return self
+}
+
+// FormatExamplesHelp creates a list of lines of help output for the examples
+// added with Example. The list is empty if there are no examples.
+func (self *OptionSet) FormatExamplesHelp() []string {
+	out := []string{}
+	indent := strings.Repeat(" ", self.helpIndent)
+	for _, ex := range self.examples {
+		out = append(out, indent+self.exampleCommand(ex))
+		if ex.description != "" {
+			out = append(out, indent+"    "+ex.description)
+		}
+	}
+	return out
+}
+
+// Return the full command line of an example, starting with the program and
+// command names.
+func (self *OptionSet) exampleCommand(ex example) string {
+	parts := []string{filepath.Base(os.Args[0])}
+	if path := self.commandName(); path != "" {
+		parts = append(parts, path)
+	}
+	if ex.cmdline != "" {
+		parts = append(parts, ex.cmdline)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,59 @@
+package miniflags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_Example(t *testing.T) {
+	prog := filepath.Base(os.Args[0])
+	build := NewOptionSet().
+		Example("-f main.go", "Rebuild main.go").
+		Example("", "")
+	NewOptionSet().AddCommand(Command("build", build, nil, ""))
+	want := strings.Join([]string{
+		"  PROG build -f main.go",
+		"      Rebuild main.go",
+		"  PROG build",
+	}, "\n")
+	got := strings.Join(build.FormatExamplesHelp(), "\n")
+	if m := checkValErr(t, strings.Replace(want, "PROG", prog, -1), got, "", nil); m != "" {
+		t.Error(m)
+	}
+	data := build.HelpData()
+	if m := checkValErr(t, HelpEntry{Names: prog + " build -f main.go", Help: "Rebuild main.go"}, data.Examples[0], "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Description_Epilog(t *testing.T) {
+	var lines []string
+	savedEmit := Emit
+	defer func() { Emit = savedEmit }()
+	Emit = func(a ...interface{}) {
+		lines = append(lines, strings.TrimSuffix(fmt.Sprintln(a...), "\n"))
+	}
+	oset := NewOptionSet().
+		Add(Option("h", func() {}, "").Hide()).
+		UsageHeader("Usage: prog").
+		Description("Prog does things.\nMany things.").
+		Epilog("Report bugs to <bugs@example.com>.\nDocs: https://example.com/docs\n")
+	Usage(oset)
+	want := strings.Join([]string{
+		"Usage: prog",
+		"",
+		"Prog does things.",
+		"Many things.",
+		"",
+		"Options:",
+		"",
+		"Report bugs to <bugs@example.com>.",
+		"Docs: https://example.com/docs",
+	}, "\n")
+	if m := checkValErr(t, want, strings.Join(lines, "\n"), "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,110 @@
+package miniflags
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// The maximum depth of response files that refer to other response files
+const maxResponseDepth = 10
+
+// Stdin is the reader used when the parser needs to read from standard input,
+// for example to get arguments for ArgsFromStdin. The default is os.Stdin.
+// This can be replaced by the client to substitute a different source.
+var Stdin io.Reader = os.Stdin
+
+// AllowResponseFiles enables response files in this OptionSet. When enabled,
+// ParseArgs replaces each argument of the form "@path" with the arguments
+// read from the named file before any other processing. The file contains
+// one argument per line; leading and trailing white space is removed, and
+// blank lines and lines starting with '#' are ignored. Response files may
+// refer to other response files. Arguments after a terminator (see
+// Terminators) are not expanded. Returns self so that calls can be chained.
+func (self *OptionSet) AllowResponseFiles() *OptionSet {
+	self.responseFiles = true
+	return self
+}
+
+// ArgsFromStdin designates token (such as "-@" or "--args-from-stdin") as an
+// argument that is replaced by arguments read from Stdin, one per line, using
+// the same format as response files. This lets other programs pipe a list of
+// arguments into the command. Stdin is read at most once; if the token appears
+// again it is removed without adding any arguments. As with response files,
+// the token is not recognized after a terminator. Returns self so that
+// calls can be chained.
+func (self *OptionSet) ArgsFromStdin(token string) *OptionSet {
+	self.stdinToken = token
+	return self
+}
+
+// Return a copy of args with any "@path" arguments replaced by the contents
+// of the named files (if response files are enabled) and any stdin token
+// replaced by the arguments read from Stdin.
+func (self *OptionSet) expandArgs(args []string) ([]string, error) {
+	stdinRead := false
+	var expand func(args []string, depth int) ([]string, error)
+
+	// depth is the current nesting level of response files
+	expand = func(args []string, depth int) ([]string, error) {
+		out := []string{}
+		for i, arg := range args {
+			var lines []string
+			var err error
+			switch {
+			case self.isTerminator(arg):
+				return append(out, args[i:]...), nil
+			case self.stdinToken != "" && arg == self.stdinToken:
+				if !stdinRead {
+					stdinRead = true
+					lines, err = readArgs(Stdin, "standard input")
+				}
+			case self.responseFiles && len(arg) >= 2 && arg[0] == '@':
+				if depth >= maxResponseDepth {
+					return nil, fmt.Errorf("Response files nested too deeply at '%s'", arg)
+				}
+				lines, err = readResponseFile(arg[1:])
+			default:
+				out = append(out, arg)
+				continue
+			}
+			if err == nil {
+				lines, err = expand(lines, depth+1)
+			}
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, lines...)
+		}
+		return out, nil
+	}
+	return expand(args, 0)
+}
+
+// Read the arguments contained in the named response file.
+func readResponseFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading response file: %v", err)
+	}
+	defer file.Close()
+	return readArgs(file, "response file")
+}
+
+// Read arguments from r, one per line, trimming white space and skipping
+// blank lines and '#' comments. Desc describes r for error messages.
+func readArgs(r io.Reader, desc string) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %v", desc, err)
+	}
+	args := []string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			args = append(args, line)
+		}
+	}
+	return args, nil
+}
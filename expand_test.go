@@ -0,0 +1,77 @@
+package miniflags
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_AllowResponseFiles(t *testing.T) {
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	outer := filepath.Join(dir, "outer.txt")
+	inner := filepath.Join(dir, "inner.txt")
+	loop := filepath.Join(dir, "loop.txt")
+	os.WriteFile(outer, []byte("# comment\n  -n\n3\n\na b\n@"+inner+"\n"), 0666)
+	os.WriteFile(inner, []byte("--list=x\r\n"), 0666)
+	os.WriteFile(loop, []byte("@"+loop), 0666)
+
+	var n int
+	var list []string
+	var tests = []struct {
+		input     []string
+		want      []string
+		errPrefix string
+	}{
+		{[]string{"@", "-n4"}, []string{"@"}, ""},
+		{[]string{"@" + outer, "c"}, []string{"a b", "c"}, ""},
+		{[]string{"@" + filepath.Join(dir, "missing")}, nil, "Error reading response file"},
+		{[]string{"@" + loop}, nil, "Response files nested too deeply"},
+	}
+	for _, test := range tests {
+		args, err := NewOptionSet().
+			Option("n", &n, "").
+			Option("list", &list, "").
+			AllowResponseFiles().
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, args, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+	}
+	if n != 3 || len(list) != 1 || list[0] != "x" {
+		t.Errorf("Got n=%d, list=%v; expected 3, [x]", n, list)
+	}
+}
+
+func Test_OptionSet_ArgsFromStdin(t *testing.T) {
+	defer func() { Stdin = os.Stdin }()
+	var n int
+	var tests = []struct {
+		stdin string
+		input []string
+		want  []string
+		wantN int
+	}{
+		{"a\n-n5\n\n# comment\nb c\n", []string{"x", "-@", "y"}, []string{"x", "a", "b c", "y"}, 5},
+		{"a\n", []string{"-@", "-@"}, []string{"a"}, 0},
+		{"a\n", []string{"x"}, []string{"x"}, 0},
+	}
+	for _, test := range tests {
+		n = 0
+		Stdin = strings.NewReader(test.stdin)
+		args, err := NewOptionSet().
+			Option("n", &n, "").
+			ArgsFromStdin("-@").
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, args, "", err); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantN, n, "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
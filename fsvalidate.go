@@ -0,0 +1,96 @@
+package miniflags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The validators in this file check string values that name paths in the
+// file system. They accept values that are not strings. The checks are made
+// when the value is parsed, so the file system may change before the program
+// uses the path.
+
+// Return a Validator for path values that applies check to each string
+// value, and reports the value as an error with the requirement if check
+// returns false.
+func pathValidator(requirement string, check func(path string) bool) Validator {
+	return func(name string, value interface{}) error {
+		if path, ok := value.(string); ok && !check(path) {
+			return fmt.Errorf("value %s for %s must be %s", path, name, requirement)
+		}
+		return nil
+	}
+}
+
+// PathExists returns a Validator requiring a path to an existing file or
+// directory.
+func PathExists() Validator {
+	return pathValidator("an existing file or directory", func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+}
+
+// IsFile returns a Validator requiring a path to an existing file that is not
+// a directory.
+func IsFile() Validator {
+	return pathValidator("an existing file", func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && !info.IsDir()
+	})
+}
+
+// IsDir returns a Validator requiring a path to an existing directory.
+func IsDir() Validator {
+	return pathValidator("an existing directory", func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && info.IsDir()
+	})
+}
+
+// Readable returns a Validator requiring a path to an existing file or
+// directory that can be opened for reading.
+func Readable() Validator {
+	return pathValidator("a readable file or directory", func(path string) bool {
+		file, err := os.Open(path)
+		if err == nil {
+			file.Close()
+		}
+		return err == nil
+	})
+}
+
+// Writable returns a Validator requiring a path to an existing file that can
+// be opened for writing, or to a directory in which files can be created.
+// The file is not changed.
+func Writable() Validator {
+	return pathValidator("a writable file or directory", func(path string) bool {
+		info, err := os.Stat(path)
+		if err != nil {
+			return false
+		}
+		var file *os.File
+		if info.IsDir() {
+			if file, err = os.CreateTemp(path, ".writable"); err == nil {
+				defer os.Remove(file.Name())
+			}
+		} else {
+			file, err = os.OpenFile(path, os.O_WRONLY, 0)
+		}
+		if err == nil {
+			file.Close()
+		}
+		return err == nil
+	})
+}
+
+// ParentExists returns a Validator requiring a path whose parent directory
+// exists, so that a file can be created there, as for an output file that
+// may not exist yet.
+func ParentExists() Validator {
+	return pathValidator("in an existing directory", func(path string) bool {
+		info, err := os.Stat(filepath.Dir(path))
+		return err == nil && info.IsDir()
+	})
+}
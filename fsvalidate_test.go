@@ -0,0 +1,56 @@
+package miniflags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_PathValidators(t *testing.T) {
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	var tests = []struct {
+		validator Validator
+		path      string
+		errPrefix string
+	}{
+		{PathExists(), file, ""},
+		{PathExists(), dir, ""},
+		{PathExists(), missing, "value " + missing + " for --path must be an existing file or directory"},
+		{IsFile(), file, ""},
+		{IsFile(), dir, "value " + dir + " for --path must be an existing file"},
+		{IsFile(), missing, "value " + missing + " for --path must be an existing file"},
+		{IsDir(), dir, ""},
+		{IsDir(), file, "value " + file + " for --path must be an existing directory"},
+		{Readable(), file, ""},
+		{Readable(), missing, "value " + missing + " for --path must be a readable file or directory"},
+		{Writable(), file, ""},
+		{Writable(), dir, ""},
+		{Writable(), missing, "value " + missing + " for --path must be a writable file or directory"},
+		{ParentExists(), missing, ""},
+		{ParentExists(), filepath.Join(missing, "x"), "value " + filepath.Join(missing, "x") + " for --path must be in an existing directory"},
+		{ParentExists(), filepath.Join(file, "x"), "value " + filepath.Join(file, "x") + " for --path must be in an existing directory"},
+		{IsFile(), "", "value  for --path must be an existing file"},
+	}
+	for _, test := range tests {
+		err := test.validator("--path", test.path)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.path, m)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if m := checkValErr(t, 1, len(entries), "", err); m != "" {
+		t.Error("Writable left a file behind:", m)
+	}
+	if m := checkValErr(t, nil, IsFile()("--n", 3), "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,107 @@
+package miniflags
+
+import (
+	"strings"
+)
+
+// HelpEntry describes one option, positional argument or command for a help
+// template; see HelpData.
+type HelpEntry struct {
+	Names      string   // The names as shown in help, e.g. "-n, --number" or "SRC..."
+	ValueName  string   // Any parameter name, e.g. "=NUM" or "[=WHEN]"
+	Help       string   // The help text, without a parameter name prefix
+	Default    string   // The default value, or "" if it is a zero value; see ShowDefaults
+	Env        []string // Environment variables that supply the value; see Env
+	Required   bool     // The option must be given; see Required
+	Choices    []string // The accepted values, for a target that implements Chooser
+	Tags       []string // Badges attached with Tag
+	Deprecated string   // The note given to Deprecated, or "" if not deprecated
+}
+
+// HelpSection is a group of options for a help template: the options before
+// the first section header, or the options after a section header.
+type HelpSection struct {
+	Header  string      // The section header, or "" for the first group
+	Options []HelpEntry // The options in this section, in help order
+}
+
+// HelpData is the data passed to a help template, as by the helptemplate
+// subpackage, and used by FormatMarkdown.
+type HelpData struct {
+	Header        string        // The usage header line
+	Description   string        // The text set with Description
+	Arguments     []HelpEntry   // The positional arguments, and any set with ArgsHelp
+	Commands      []HelpEntry   // The commands, not including section headers
+	Sections      []HelpSection // The options, grouped by section
+	GlobalOptions []HelpEntry   // Options inherited from the sets this is a command of
+	Examples      []HelpEntry   // The examples; Names is the command line and Help the description
+	Epilog        string        // The text set with Epilog
+}
+
+// HelpData returns the information about this set used by help templates.
+func (self *OptionSet) HelpData() HelpData {
+	data := HelpData{Header: self.usageHeader(), Sections: []HelpSection{{}},
+		Description: self.description, Epilog: self.epilog}
+	for _, def := range self.argumentDefs() {
+		data.Arguments = append(data.Arguments, self.helpEntry(def, def.positionalName(), ""))
+	}
+	for _, cmd := range self.commands {
+		if !cmd.isSectionHeader() {
+			data.Commands = append(data.Commands, HelpEntry{Names: cmd.Name(), Help: cmd.help})
+		}
+	}
+	for _, def := range self.helpList(AutoHelp) {
+		switch {
+		case def.hidden:
+		case def.isSectionHeader():
+			data.Sections = append(data.Sections, HelpSection{Header: def.help})
+		default:
+			section := &data.Sections[len(data.Sections)-1]
+			section.Options = append(section.Options, self.optionHelpEntry(def))
+		}
+	}
+	for set := self.parent; set != nil; set = set.parent {
+		for _, def := range set.list {
+			if !def.hidden && !def.isSectionHeader() {
+				data.GlobalOptions = append(data.GlobalOptions, set.optionHelpEntry(def))
+			}
+		}
+	}
+	for _, ex := range self.examples {
+		data.Examples = append(data.Examples, HelpEntry{Names: self.exampleCommand(ex), Help: ex.description})
+	}
+	return data
+}
+
+// Return the help template entry for an option.
+func (self *OptionSet) optionHelpEntry(def *OptionDef) HelpEntry {
+	valName, _ := def.splitHelp()
+	if def.optionalParam && valName != "" {
+		valName = "[" + valName + "]"
+	}
+	return self.helpEntry(def, def.formatNames(self.isNegatable(def)), valName)
+}
+
+// Return the help template entry for an option or positional argument with
+// the given names and parameter name.
+func (self *OptionSet) helpEntry(def *OptionDef, names, valName string) HelpEntry {
+	_, help := def.splitHelp()
+	if def.positional {
+		help = def.help
+	}
+	var choices []string
+	if chooser, ok := def.target.(Chooser); ok {
+		choices = chooser.Choices()
+	}
+	return HelpEntry{
+		Names:      names,
+		ValueName:  valName,
+		Help:       strings.TrimSpace(help),
+		Default:    def.formatDefault(),
+		Env:        def.allEnvKeys(),
+		Required:   def.required,
+		Choices:    choices,
+		Tags:       def.tags,
+		Deprecated: def.deprecated,
+	}
+}
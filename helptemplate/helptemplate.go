@@ -0,0 +1,54 @@
+/*
+Package helptemplate renders the usage message of a miniflags.OptionSet with
+a text/template instead of the built-in layout. It is kept separate so that
+programs that don't use templates don't include the template engine. The
+template is executed with the miniflags.HelpData of the set whose usage is
+shown, for example:
+
+	{{.Header}}
+	{{range .Sections}}{{.Header}}
+	{{range .Options}}  {{printf "%-24s" (print .Names .ValueName)}}{{.Help}}
+	{{end}}{{end}}
+*/
+package helptemplate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/jsthayer/miniflags"
+)
+
+// Use parses text as a template and sets it, with the UsageFunc method, to
+// render the usage message of set and its commands. Each line of the result
+// is written with the Emit method of the set; if executing the template
+// fails, the error is written instead. Returns an error if the template
+// can't be parsed.
+func Use(set *miniflags.OptionSet, text string) error {
+	tmpl, err := template.New("help").Parse(text)
+	if err != nil {
+		return fmt.Errorf("Error in help template: %v", err)
+	}
+	set.UsageFunc(func(defs *miniflags.OptionSet) {
+		lines, err := Render(defs, tmpl)
+		if err != nil {
+			lines = []string{err.Error()}
+		}
+		for _, line := range lines {
+			defs.Emit(line)
+		}
+	})
+	return nil
+}
+
+// Render executes tmpl with the help data of set and returns the lines of
+// output.
+func Render(set *miniflags.OptionSet, tmpl *template.Template) ([]string, error) {
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, set.HelpData()); err != nil {
+		return nil, fmt.Errorf("Error in help template: %v", err)
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n"), nil
+}
@@ -0,0 +1,59 @@
+package helptemplate
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/jsthayer/miniflags"
+)
+
+func Test_Use(t *testing.T) {
+	num := 3
+	var src string
+	var verbose bool
+	oset := miniflags.NewOptionSet().
+		Add(miniflags.Option("n number", &num, "=NUM; Number value").Env("NUM")).
+		Section("Output:").
+		Add(miniflags.Option("v verbose", &verbose, "Verbose output").Required()).
+		Add(miniflags.Option("x", func() {}, "").Hide()).
+		Positional("SRC", &src, "Source file").
+		UsageHeader("Usage: prog SRC")
+	err := Use(oset, `{{.Header}}
+{{range .Arguments}}{{.Names}}: {{.Help}}
+{{end}}{{range .Sections}}[{{.Header}}]
+{{range .Options}}{{.Names}}{{.ValueName}} {{.Help}}{{if .Default}} ({{.Default}}){{end}}{{range .Env}} ${{.}}{{end}}{{if .Required}} !{{end}}
+{{end}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []string
+	oset.EmitFunc(func(a ...interface{}) {
+		lines = append(lines, a[0].(string))
+	})
+	oset.ErrorHandling(miniflags.ContinueOnError).ParseArgs([]string{"--help"})
+	want := strings.Join([]string{
+		"Usage: prog SRC",
+		"SRC: Source file",
+		"[]",
+		"-n, --number=NUM Number value (3) $NUM",
+		"[Output:]",
+		"-v, --verbose Verbose output !",
+		"-h, --help Print this help message and exit",
+	}, "\n")
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("got:\n%s\nexpected:\n%s", got, want)
+	}
+}
+
+func Test_Errors(t *testing.T) {
+	err := Use(miniflags.NewOptionSet(), "{{.Header")
+	if err == nil || err.Error() != "Error in help template: template: help:1: unclosed action" {
+		t.Errorf("unexpected error %v", err)
+	}
+	_, err = Render(miniflags.NewOptionSet(), template.Must(template.New("help").Parse("{{.Missing}}")))
+	if err == nil || !strings.HasPrefix(err.Error(), "Error in help template: ") {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package miniflags
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatMarkdown renders the help for this set as Markdown lines, for
+// generating the usage section of a README or other documentation from the
+// option definitions. The output has the usage header as a code block, the
+// description, and tables of the arguments, commands and options, with a
+// heading for each option section, followed by the examples and the epilog.
+// Default values and environment variables are always included.
+func (self *OptionSet) FormatMarkdown() []string {
+	data := self.HelpData()
+	out := []string{"```", data.Header, "```"}
+	if data.Description != "" {
+		out = append(out, "", data.Description)
+	}
+	out = appendMarkdownTable(out, "Arguments", "Argument", data.Arguments)
+	out = appendMarkdownTable(out, "Commands", "Command", data.Commands)
+	for _, section := range data.Sections {
+		title := strings.TrimSuffix(strings.TrimSpace(section.Header), ":")
+		if title == "" {
+			title = "Options"
+		}
+		out = appendMarkdownTable(out, title, "Option", section.Options)
+	}
+	out = appendMarkdownTable(out, "Global options", "Option", data.GlobalOptions)
+	if len(data.Examples) > 0 {
+		out = append(out, "", "### Examples")
+		for _, ex := range data.Examples {
+			out = append(out, "", "```", ex.Names, "```")
+			if ex.Help != "" {
+				out = append(out, ex.Help)
+			}
+		}
+	}
+	if data.Epilog != "" {
+		out = append(out, "", data.Epilog)
+	}
+	return out
+}
+
+// Append a heading and a table of entries to out, unless entries is empty.
+func appendMarkdownTable(out []string, title, kind string, entries []HelpEntry) []string {
+	if len(entries) == 0 {
+		return out
+	}
+	out = append(out, "", "### "+title, "", fmt.Sprintf("| %s | Description |", kind), "| --- | --- |")
+	for _, entry := range entries {
+		help := entry.Help
+		if entry.Default != "" {
+			help += fmt.Sprintf(" (default: %s)", entry.Default)
+		}
+		if entry.Required {
+			help += " (required)"
+		}
+		if entry.Deprecated != "" {
+			help += fmt.Sprintf(" (deprecated: %s)", entry.Deprecated)
+		}
+		if len(entry.Env) > 0 {
+			help += fmt.Sprintf(" [env: %s]", strings.Join(entry.Env, ", "))
+		}
+		for _, tag := range entry.Tags {
+			help += " [" + tag + "]"
+		}
+		out = append(out, fmt.Sprintf("| `%s` | %s |", entry.Names+entry.ValueName, markdownCell(help)))
+	}
+	return out
+}
+
+// Escape text for use in a Markdown table cell.
+func markdownCell(text string) string {
+	text = strings.Replace(strings.TrimSpace(text), "|", "\\|", -1)
+	return strings.Replace(text, "\n", "<br>", -1)
+}
@@ -0,0 +1,64 @@
+package miniflags
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_FormatMarkdown(t *testing.T) {
+	num := 3
+	var src, mode string
+	var verbose bool
+	oset := NewOptionSet().
+		Add(Option("n number", &num, "=NUM; Number value").Env("NUM")).
+		Section("Output:").
+		Add(Option("v verbose", &verbose, "Verbose output").Required()).
+		Option("mode", &mode, "=MODE; Either a|b").
+		Add(Option("h", func() {}, "").Hide()).
+		Positional("SRC", &src, "Source file").
+		UsageHeader("Usage: prog SRC").
+		Description("Does things.").
+		Example("-n 4 a.txt", "Use four").
+		Epilog("See the docs.")
+	want := strings.Join([]string{
+		"```",
+		"Usage: prog SRC",
+		"```",
+		"",
+		"Does things.",
+		"",
+		"### Arguments",
+		"",
+		"| Argument | Description |",
+		"| --- | --- |",
+		"| `SRC` | Source file |",
+		"",
+		"### Options",
+		"",
+		"| Option | Description |",
+		"| --- | --- |",
+		"| `-n, --number=NUM` | Number value (default: 3) [env: NUM] |",
+		"",
+		"### Output",
+		"",
+		"| Option | Description |",
+		"| --- | --- |",
+		"| `-v, --verbose` | Verbose output (required) |",
+		"| `--mode=MODE` | Either a\\|b |",
+		"",
+		"### Examples",
+		"",
+		"```",
+		filepath.Base(os.Args[0]) + " -n 4 a.txt",
+		"```",
+		"Use four",
+		"",
+		"See the docs.",
+	}, "\n")
+	got := strings.Join(oset.FormatMarkdown(), "\n")
+	if m := checkValErr(t, want, got, "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -34,7 +34,7 @@ option is seen, or setting a string using a finite set of choices. Custom
 actions may be implemented with a symple anonymous function.
 
 - If no help options are defined, this package has an optional default
-implementation that prints the usage text and exits the program.
+implementation that prints the usage text and returns ErrHelp.
 
 - A custom action may be defined to handle non-option arguments instead
 of the default of appending them to an arguments list.
@@ -42,6 +42,13 @@ of the default of appending them to an arguments list.
 - Section headers may be defined to separate groups of options in the help
 output.
 
+- Positional arguments may be given names and typed targets, so that they are
+converted, checked for presence, and shown in the usage message.
+
+- Subcommands, as in "tool build -v", may be defined with their own options
+and handler functions; the command name selects the options used to parse the
+rest of the command line.
+
 Example usage:
 	import "github.com/jsthayer/miniflags"
 
@@ -54,8 +61,8 @@ Example usage:
 
 	args, err := miniflags.NewOptionSet().
 		Option("n number", &num, "=NUM; Number value (default=3)").
-		Option("c color ", miniflags.AlternativesOption(&color, []string{"red", "green", "blue"}),
-			"=COLOR; Color (red, green or blue)").
+		Option("c color ", miniflags.Choices(&color, []string{"red", "green", "blue"}),
+			"=COLOR; Color").
 		Option("  list  ", &str, "=ITEM; String list value").
 		Option("f flag  ", &flag, "Boolean flag").
 		Option("E eight ", func() { num = 8 }, "Set number value to 8").
@@ -66,7 +73,7 @@ Using the above definitions in a test such as "test_prog -h" produces the follow
 	Usage: test_prog [ options and/or arguments ]
 	Options:
 	  -n, --number=NUM  Number value (default=3)
-	  -c, --color=COLOR Color (red, green or blue)
+	  -c, --color=COLOR Color (one of: red, green, blue)
 	  --list=ITEM       String list value
 	  -f, --flag        Boolean flag
 	  -E, --eight       Set number value to 8
@@ -89,8 +96,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // OptionDef structs are used to specify options.
@@ -98,20 +108,105 @@ type OptionDef struct {
 	names  string      // Space-separated long and/or short option names
 	target interface{} // The variable receiving the option or a setter function
 	help   string      // Description of this option in the usage help text
+
+	envKeys      []string // Environment variables that may supply a value, in order
+	envPrefix    string   // Prefix for the variable derived from the long name; see EnvPrefix
+	defaultEnv   string   // Environment variable that may supply the default
+	envDefaulted bool     // The default was set from defaultEnv
+	setupError   error    // Any error detected while defining this option
+
+	negates    *OptionDef // For an automatic "--no-" option, the option it negates
+	deprecated string     // Note explaining the deprecation; see Deprecated
+	maxCount   int        // The maximum times the option may be given, if > 0
+	minCount   int        // The minimum times the option must be given; see MinCount
+	positional bool       // This defines a positional argument rather than an option
+	minArgs    int        // For a positional argument, the minimum number of arguments
+	maxArgs    int        // For a positional argument, the maximum number, or -1 for no limit
+
+	defaultValue interface{} // A copy of the variable target's value when the option was added
+
+	hiddenNames map[string]bool // Names that are accepted but not shown in help
+	hidden      bool            // The whole option is left out of help output
+
+	optionalParam bool                  // The parameter may be left out; see Implicit
+	implicit      string                // The value used when an optional parameter is left out
+	required      bool                  // The option must be given
+	advanced      bool                  // The option is left out of brief help
+	metavar       string                // The parameter name for help output, if set with Metavar
+	tags          []string              // Badges shown at the end of the help text
+	ephemeral     bool                  // The value is not saved by RememberValues
+	validators    []Validator           // Checks on converted values; see Validate
+	dedupe        bool                  // Repeated values are dropped from a list target; see Unique
+	normalizers   []func(string) string // Rewrite raw parameters; see Normalize
 }
 
 // OptionSet holds a set of OptionDef structures that defines the valid options
-// for a parsing operation.
+// for a parsing operation. Parses of one set by different goroutines are
+// serialized by a lock, since they share the targets of the options; see
+// ParseArgsWith.
 type OptionSet struct {
 	list       []*OptionDef          // The options in this set in original order
 	index      map[string]*OptionDef // Options indexed by names
 	argAction  *OptionDef            // Optional action for non-option arguments
+	argsHelp   *OptionDef            // Optional help entry for non-option arguments
+	unknownAct *OptionDef            // Optional action for unrecognized options
 	setupError error                 // Any error detected in the definition phase
+
+	allowUnknown      bool     // Collect unknown options instead of reporting an error
+	responseFiles     bool     // Expand "@file" arguments into the contents of the file
+	stdinToken        string   // Argument that is replaced by arguments read from stdin
+	strict            bool     // Require complete metadata for every option
+	terminators       []string // Arguments that end option processing; nil means "--"
+	negations         bool     // Accept "--no-NAME" for long boolean options
+	promptMissing     bool     // Prompt on a terminal for missing required options
+	rawShortParams    bool     // Don't strip a '=' delimiter from joined short parameters
+	warnUnknownConfig bool     // Unknown config file keys are warnings rather than errors
+	envPrefix         string   // Prefix of environment variables derived from option names
+	precedence        []Source // The order of the sources of option values; see Precedence
+	statePath         string   // The file of remembered option values; see RememberValues
+	configAuth        string   // Authorization header for config file URLs
+
+	argContext func(arg string) *OptionSet // Creates option contexts for arguments
+
+	header        string      // The usage header for this set, if not the default
+	helpIndent    int         // Spaces before each entry in help output
+	helpColumn    int         // Width of the left help column; 0 is the default, < 0 is automatic
+	wrapWidth     int         // Width to wrap help lines to, if > 0
+	alignSections bool        // Size the left help column for each section separately
+	colors        *HelpColors // Palette for colored help output, if enabled
+	sortHelp      bool        // Sort options by name within each help section
+	showDefaults  bool        // Show the default values of options in help
+	examples      []example   // Example command lines for help output
+	epilog        string      // Text shown at the end of the usage message
+	description   string      // Text shown after the usage header
+
+	sources         map[*OptionDef]ValueSource  // Where each option got its value in the last ParseArgs; see Source
+	deprecatedCount map[*OptionDef]int          // Uses of deprecated options in all parses
+	mu              *sync.Mutex                 // Held during a parse, shared with translated sets; see ParseArgsWith
+	config          map[*OptionDef]*configValue // Values loaded from config files
+
+	matcher        *matcher                                // Precomputed name lookup tables; see Compile
+	constraints    []*constraint                           // Rules about which options may be given together
+	positionals    []*OptionDef                            // Positional argument definitions in order
+	argsValidators []ArgsValidator                         // Checks on the returned non-option arguments
+	finally        []func() error                          // Functions called at the end of a successful parse
+	allErrors      bool                                    // Collect problems and report them together; see AllErrors
+	errorHandling  ErrorHandling                           // How parse errors are handled; see ErrorHandling
+	emit           func(a ...interface{})                  // Writes message lines; see EmitFunc
+	usage          func(defs *OptionSet)                   // Displays the usage message; see UsageFunc
+	onError        func(defs *OptionSet, a ...interface{}) // Handles parse errors; see OnErrorFunc
+	warning        func(message string)                    // Receives warnings; see WarningFunc
+	emitWarnings   bool                                    // Write all warnings with Emit; see EmitWarnings
+
+	commands []*CommandDef // Subcommands in original order
+	command  *CommandDef   // The command selected by the last ParseArgs, if any
+	parent   *OptionSet    // The set that this one is a command of, if any
 }
 
 // Emit is called when the option parser needs to write a user-visible message
 // line. The default action is to write the line to stderr. This function can
-// be replaced by the client to substitute different behavior.
+// be replaced by the client to substitute different behavior. The EmitFunc
+// method sets the function for a single OptionSet instead.
 var Emit = func(a ...interface{}) {
 	fmt.Fprintln(os.Stderr, a...)
 }
@@ -119,50 +214,133 @@ var Emit = func(a ...interface{}) {
 // OnError is called when the option parser encounters an error. Defs is the
 // current list of OptionDef structures. The default action is to print the
 // usage message, followed by any values provided in "a", then calling
-// os.Exit(1).  This function can be replaced by the client to substitute
-// different behavior.
+// os.Exit(ErrorExitStatus).  This function can be replaced by the client to
+// substitute different behavior. It is not called for an OptionSet whose
+// ErrorHandling is ContinueOnError or PanicOnError. The OnErrorFunc method
+// sets the function for a single OptionSet instead.
 var OnError func(defs *OptionSet, a ...interface{})
 
 // Args contains the non-option arguments found by the most recent call to
 // ParseArgs.  A copy of this list is also returned by the ParseArgs function.
+//
+// Deprecated: Args is shared by all parses, so concurrent or repeated parses
+// overwrite each other's results. Use the Args field of the ParseResult
+// returned by Parse, or the list returned by ParseArgs, instead.
 var Args []string
 
+// UnknownArgs contains the unrecognized options found by the most recent call
+// to ParseArgs on an OptionSet with AllowUnknown enabled. Each entry is the
+// option as it appeared on the command line, including any parameter that was
+// joined to it.
+//
+// Deprecated: Use the Unknown field of the ParseResult returned by Parse
+// instead.
+var UnknownArgs []string
+
 // UsageHeader is the first part of the message displayed by the Usage
 // function.  The default shows "Usage:", followed by the program name,
-// followed by generic options choices. This string can be replaced by the
-// client to get a different header.
+// followed by generic options choices; while it is unchanged, the header of
+// each OptionSet is instead generated from its definitions, showing its
+// required options and positional arguments. This string can be replaced by
+// the client to get a different header. The UsageHeader method sets the header for
+// a single OptionSet instead.
 var UsageHeader = fmt.Sprintf("Usage: %s [ options and/or arguments ]", filepath.Base(os.Args[0]))
 
+// The initial value of UsageHeader, used to detect whether the client has
+// replaced it
+var defaultUsageHeader = UsageHeader
+
+// BriefUsage displays a short usage message for an OptionSet that has
+// options marked with Advanced, when the automatic "-h" option is given; the
+// automatic "--help" option shows the full message with Usage. The default
+// shows the usage header, the options that are not advanced, and a note
+// about "--help". This function can be replaced by the client to substitute
+// different behavior.
+var BriefUsage func(defs *OptionSet)
+
 // Usage displays the command line usage help for the program, using the given
 // list of OptionDef structures. The default is to print the usage header,
 // followed by any help for non-option arguments, followed by help text for
 // each option. This function can be replaced by the client to substitute
-// different behavior.
+// different behavior. The UsageFunc method sets the function for a single
+// OptionSet instead.
 var Usage func(defs *OptionSet)
 
 // AutoHelp enables the automatic generation of help options. If neither "-h"
 // or "--help" options are defined and AutoHelp is true, then the above named
 // options will automatically be added to the option definition list. The
-// action for these options will be to print the usage message and exit the
-// program with a zero status. If AutoHelp is set to false, then the automatic
-// help options will not be added.
+// action for these options will be to print the usage message and return
+// ErrHelp from ParseArgs. If AutoHelp is set to false, then the automatic
+// help options will not be added. When the options follow a command name, as
+// in "tool build -h", the usage message is for that command: its header shows
+// the command names, and the options inherited from the enclosing sets are
+// listed separately as global options. Similarly, if an OptionSet has
+// commands but none named "help", a "help" command is added that shows the
+// usage message of the command named after it, as in "tool help build".
 var AutoHelp = true
 
 // Set the implementations for OnError and Usage here so they don't clutter the
 // documentation.
 func init() {
 	OnError = func(defs *OptionSet, a ...interface{}) {
-		Usage(defs)
-		Emit()
-		Emit(a...)
-		os.Exit(1)
+		defs.showUsage()
+		defs.Emit()
+		defs.Emit(a...)
+		os.Exit(ErrorExitStatus)
+	}
+
+	BriefUsage = func(defs *OptionSet) {
+		defs.Emit(defs.usageHeader())
+		defs.Emit(defs.colorHeader("Options:"))
+		for _, line := range defs.FormatBriefOptionsHelp() {
+			defs.Emit(line)
+		}
+		defs.Emit()
+		defs.Emit("Use --help to show all options.")
 	}
 
 	Usage = func(defs *OptionSet) {
-		Emit(UsageHeader)
-		Emit("Options:")
+		defs.Emit(defs.usageHeader())
+		if defs.description != "" {
+			defs.Emit()
+			for _, line := range strings.Split(defs.description, "\n") {
+				defs.Emit(line)
+			}
+			defs.Emit()
+		}
+		if lines := defs.FormatArgumentsHelp(); len(lines) > 0 {
+			defs.Emit(defs.colorHeader("Arguments:"))
+			for _, line := range lines {
+				defs.Emit(line)
+			}
+		}
+		if lines := defs.FormatCommandsHelp(); len(lines) > 0 {
+			defs.Emit(defs.colorHeader("Commands:"))
+			for _, line := range lines {
+				defs.Emit(line)
+			}
+		}
+		defs.Emit(defs.colorHeader("Options:"))
 		for _, line := range defs.FormatOptionsHelp() {
-			Emit(line)
+			defs.Emit(line)
+		}
+		if lines := defs.FormatGlobalOptionsHelp(); len(lines) > 0 {
+			defs.Emit(defs.colorHeader("Global options:"))
+			for _, line := range lines {
+				defs.Emit(line)
+			}
+		}
+		if lines := defs.FormatExamplesHelp(); len(lines) > 0 {
+			defs.Emit(defs.colorHeader("Examples:"))
+			for _, line := range lines {
+				defs.Emit(line)
+			}
+		}
+		if defs.epilog != "" {
+			defs.Emit()
+			for _, line := range strings.Split(defs.epilog, "\n") {
+				defs.Emit(line)
+			}
 		}
 	}
 }
@@ -196,19 +374,98 @@ func FlagResetOption(target *bool) func() {
 	}
 }
 
+// Setter is implemented by Option target values that handle the parameter
+// themselves. Set is called with the parameter each time the option is
+// given, and any error is reported as an error with the option.
+type Setter interface {
+	Set(value string) error
+}
+
+// Chooser is implemented by Option target values that accept only a fixed
+// set of parameter values. The choices are listed in the option's help text,
+// as in "(one of: red, green, blue)".
+type Chooser interface {
+	Choices() []string
+}
+
+// Alternatives is the Option target value created by Choices. It implements
+// Setter and Chooser.
+type Alternatives struct {
+	target  *string  // The variable receiving the chosen value
+	choices []string // The accepted values
+}
+
 // AlternativesOption is a factory function that can be called to create an
 // Option target value that will only accept one of the set of
-// alternative values specified in choices.
+// alternative values specified in choices. Use Choices instead to also show
+// the choices in the option's help text.
 func AlternativesOption(target *string, choices []string) func(val string) error {
-	return func(val string) error {
-		for _, choice := range choices {
-			if choice == val {
-				*target = val
+	return Choices(target, choices).Set
+}
+
+// Choices is a factory function that can be called to create an Option target
+// value that will only accept one of the set of alternative values specified
+// in choices, like AlternativesOption. The choices are shown in the option's
+// help text.
+func Choices(target *string, choices []string) *Alternatives {
+	return &Alternatives{target: target, choices: choices}
+}
+
+// Set stores value in the target variable if it is one of the choices, and
+// returns an error otherwise.
+func (self *Alternatives) Set(value string) error {
+	for _, choice := range self.choices {
+		if choice == value {
+			*self.target = value
+			return nil
+		}
+	}
+	return fmt.Errorf("Invalid parameter value '%s'", value)
+}
+
+// Choices returns the accepted values.
+func (self *Alternatives) Choices() []string {
+	return self.choices
+}
+
+// IntAlternatives is the Option target value created by
+// IntAlternativesOption. It implements Setter and Chooser.
+type IntAlternatives struct {
+	target  *int  // The variable receiving the chosen value
+	choices []int // The accepted values
+}
+
+// IntAlternativesOption is a factory function that can be called to create
+// an Option target value that will only accept one of the integer values
+// specified in choices, such as the compression levels 0, 1 and 9. The
+// choices are shown in the option's help text and in the error for any other
+// value.
+func IntAlternativesOption(target *int, choices []int) *IntAlternatives {
+	return &IntAlternatives{target: target, choices: choices}
+}
+
+// Set converts value to an integer and stores it in the target variable if
+// it is one of the choices, and returns an error otherwise.
+func (self *IntAlternatives) Set(value string) error {
+	n, err := strconv.ParseInt(value, 0, 0)
+	if err == nil {
+		for _, choice := range self.choices {
+			if int64(choice) == n {
+				*self.target = choice
 				return nil
 			}
 		}
-		return fmt.Errorf("Invalid parameter value '%s'", val)
 	}
+	return fmt.Errorf("Invalid parameter value '%s'; expected one of: %s", value, strings.Join(self.Choices(), ", "))
+}
+
+// Choices returns the accepted values, formatted as strings.
+func (self *IntAlternatives) Choices() []string {
+	choices := []string{}
+	for _, choice := range self.choices {
+		choices = append(choices, strconv.Itoa(choice))
+	}
+	return choices
 }
 
 // Test whether the option defined by def consumes a parameter. Returns true
@@ -235,7 +492,7 @@ func (self *OptionDef) isTargetOk() bool {
 	}
 	switch self.target.(type) {
 	case func(string) error, func() error, func(string), func(),
-		*string, *uint, *uint64, *int, *int64, *float64, *bool, *[]string:
+		*string, *uint, *uint64, *int, *int64, *float64, *bool, *[]string, Setter:
 		return true
 	default:
 		return false
@@ -248,11 +505,21 @@ func (self *OptionDef) isTargetOk() bool {
 // separated by commas. For the special case of the non-option arguments
 // def, the returned value is "<Arguments>".
 func (self *OptionDef) formatOptionNames() string {
+	return self.formatNames(false)
+}
+
+// Format the names as with formatOptionNames. If negatable is true, long
+// names are shown with a "[no-]" prefix, as in "--[no-]color".
+func (self *OptionDef) formatNames(negatable bool) string {
 	names := []string{}
 	for _, name := range strings.Split(self.names, " ") {
 		switch {
+		case self.hiddenNames[name]:
+			// accepted but not shown
 		case len(name) == 1:
 			names = append(names, "-"+name)
+		case len(name) > 1 && negatable:
+			names = append(names, "--[no-]"+name)
 		case len(name) > 1:
 			names = append(names, "--"+name)
 		}
@@ -260,6 +527,94 @@ func (self *OptionDef) formatOptionNames() string {
 	return strings.Join(names, ", ")
 }
 
+// Return the canonical name of this option: its first long name if it has
+// one, otherwise its first name.
+func (self *OptionDef) canonicalName() string {
+	first := ""
+	for _, name := range strings.Split(self.names, " ") {
+		if len(name) > 1 {
+			return name
+		}
+		if first == "" {
+			first = name
+		}
+	}
+	return first
+}
+
+// Return the canonical name of this option with its dashes, e.g. "--output"
+// or "-o", for use in messages.
+func (self *OptionDef) displayName() string {
+	name := self.canonicalName()
+	if len(name) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// Hide marks some of this option's names as hidden: they are still accepted
+// when parsing, but are not shown in help output. This is useful for legacy
+// spellings kept for backward compatibility. If no names are given, the whole
+// option is left out of the help output. Returns self so that calls can be
+// chained.
+func (self *OptionDef) Hide(names ...string) *OptionDef {
+	if len(names) == 0 {
+		self.hidden = true
+	}
+	for _, name := range names {
+		if self.hiddenNames == nil {
+			self.hiddenNames = map[string]bool{}
+		}
+		self.hiddenNames[name] = true
+	}
+	return self
+}
+
+// Implicit makes this option's parameter optional, as in the GNU style
+// "--color[=WHEN]". If the option is given without a joined parameter, the
+// value is used as if it had been given; the following argument is never
+// taken as the parameter. A parameter must be joined to the option: with '='
+// for a long option ("--color=never"), and with or without '=' for a short
+// option ("-cnever" or "-c=never"). In the help output, any parameter name is
+// shown in brackets. This has no effect on options that don't take a
+// parameter. Returns self so that calls can be chained.
+func (self *OptionDef) Implicit(value string) *OptionDef {
+	self.optionalParam = true
+	self.implicit = value
+	return self
+}
+
+// MaxCount limits the number of times this option may be given on the command
+// line to max. If it is given more often, ParseArgs reports an error such as
+// "Option '--output' given more than once" rather than silently using the
+// last value. See MinCount for the opposite limit. Returns self so that calls
+// can be chained.
+func (self *OptionDef) MaxCount(max int) *OptionDef {
+	self.maxCount = max
+	return self
+}
+
+// Check if the target of this option is a string list variable.
+func (self *OptionDef) isList() bool {
+	_, ok := self.target.(*[]string)
+	return ok
+}
+
+// Check if the target of this option is a bool variable.
+func (self *OptionDef) isBool() bool {
+	_, ok := self.target.(*bool)
+	return ok
+}
+
+// Return the option that this one negates if it is an automatic negation,
+// otherwise self.
+func (self *OptionDef) base() *OptionDef {
+	if self.negates != nil {
+		return self.negates
+	}
+	return self
+}
+
 // Option returns a new OptionDef structure given one or more space-separated
 // names (long and/or short), the target action, and a help string. If
 // the help string starts with a prefix of the form "=NAME; ", then that
@@ -271,12 +626,16 @@ func (self *OptionDef) formatOptionNames() string {
 //   func(), func() error, func(string), func(string) error
 // For most pointers, an attempt is made to convert the string parameter to the
 // target type. If successful, the new value is stored in the target.  For the
-// bool pointer, there is no parameter and the value is set to true.  For the
+// bool pointer, there is normally no parameter and the value is set to true;
+// however an explicit value may be joined with '=', as in "--flag=false" or
+// "-f=no", and is parsed with strconv.ParseBool (which is extended to also
+// accept yes/no and on/off).  For the
 // []string pointer, the parameter is appended to the slice each time the
 // option is parsed.  The function types specify custom actions with and
-// without parameters, which may or may not return errors.
+// without parameters, which may or may not return errors. A target may also be
+// any value that implements Setter, such as the one returned by Choices.
 func Option(names string, target interface{}, help string) *OptionDef {
-	return &OptionDef{names, target, help}
+	return &OptionDef{names: names, target: target, help: help}
 }
 
 // Section returns a new OptionDef that is only used as a section header
@@ -288,7 +647,7 @@ func Section(header string) *OptionDef {
 // NewOptionSet returns a new option set, optionally containing all of the
 // OptionDef structures in entires.
 func NewOptionSet(entries ...*OptionDef) *OptionSet {
-	defs := &OptionSet{index: map[string]*OptionDef{}}
+	defs := &OptionSet{index: map[string]*OptionDef{}, helpIndent: 2, mu: &sync.Mutex{}}
 	return defs.Add(entries...)
 }
 
@@ -298,6 +657,12 @@ func (self *OptionSet) Option(names string, target interface{}, help string) *Op
 	return self.Add(Option(names, target, help))
 }
 
+// OptionE is equivalent to calling AddE(Option(names, target, help)) on this
+// OptionSet.
+func (self *OptionSet) OptionE(names string, target interface{}, help string) error {
+	return self.AddE(Option(names, target, help))
+}
+
 // ArgAction sets a custom target action for non-option arguments in this OptionSet.
 // The requirements for target are the same as those for the Option call. Returns
 // self so that calls can be chained.
@@ -309,6 +674,106 @@ func (self *OptionSet) ArgAction(target interface{}) *OptionSet {
 	return self
 }
 
+// ArgsHelp describes the non-option arguments of this set in help output,
+// for use with ArgAction or when the arguments are returned by ParseArgs. The
+// name, such as "FILE...", is shown in the usage header, and the name and
+// help text are shown in the "Arguments:" block of the usage message, after
+// any positional arguments. Returns self so that calls can be chained.
+func (self *OptionSet) ArgsHelp(name, help string) *OptionSet {
+	self.argsHelp = &OptionDef{names: name, help: help, positional: true, minArgs: 1, maxArgs: 1}
+	return self
+}
+
+// ArgContext enables option contexts within one command line. Each time a
+// non-option argument is found, newContext is called with the argument and
+// returns an OptionSet (which may be nil) holding options that apply only to
+// that argument. Options following the argument are looked up in that context
+// first, then in this set, until the next non-option argument starts a new
+// context. Typically newContext creates a new record for the argument and
+// returns options that set the fields of that record, e.g. for per-file
+// settings in a batch converter. The argument itself is still handled as
+// usual. Options in the contexts are not shown in this set's help output.
+// Returns self so that calls can be chained.
+func (self *OptionSet) ArgContext(newContext func(arg string) *OptionSet) *OptionSet {
+	self.argContext = newContext
+	return self
+}
+
+// Negations enables automatic negations of boolean options. For every option
+// with a *bool target, each long name NAME may also be given as "--no-NAME",
+// which sets the target to false. This lets users override options that
+// default to true. The negations are not listed separately in the help
+// output; instead the long names are shown as "--[no-]NAME". An option
+// explicitly defined with a "no-" name takes precedence over a negation.
+// Returns self so that calls can be chained.
+func (self *OptionSet) Negations() *OptionSet {
+	self.negations = true
+	return self
+}
+
+// RawShortParameters makes parameters joined to short options be used exactly
+// as given, without removing a leading '=' delimiter. With this setting "-D=x"
+// gives the parameter "=x" rather than "x", which suits options whose values
+// may themselves start with '='. Long options are not affected, since their
+// names always end at the first '='. Returns self so that calls can be
+// chained.
+func (self *OptionSet) RawShortParameters() *OptionSet {
+	self.rawShortParams = true
+	return self
+}
+
+// Terminators sets the arguments that mark the end of options. After one of
+// them is found, all following arguments are treated as non-option arguments,
+// even if they start with a dash. The terminator itself is kept in its place
+// in the returned arguments, but is not assigned to a positional argument or
+// checked by the argument validators. The default terminator is "--"; calling
+// this method replaces it,
+// so include "--" in tokens to keep it, e.g. Terminators("--", ";") for
+// find-style syntax. Returns self so that calls can be chained.
+func (self *OptionSet) Terminators(tokens ...string) *OptionSet {
+	self.terminators = append([]string{}, tokens...)
+	return self
+}
+
+// Check if arg is one of the end of options markers for this set.
+func (self *OptionSet) isTerminator(arg string) bool {
+	if self.terminators == nil {
+		return arg == "--"
+	}
+	for _, token := range self.terminators {
+		if arg == token {
+			return true
+		}
+	}
+	return false
+}
+
+// UnknownAction sets a custom target action for unrecognized options in this
+// OptionSet. The requirements for target are the same as those for the Option
+// call; a target that takes a parameter receives the unknown option as it
+// appeared on the command line, including any parameter joined to it. This
+// action takes precedence over AllowUnknown. Returns self so that calls can be
+// chained.
+func (self *OptionSet) UnknownAction(target interface{}) *OptionSet {
+	self.unknownAct = &OptionDef{target: target}
+	if self.setupError == nil && !self.unknownAct.isTargetOk() {
+		self.setupError = fmt.Errorf("Unsupported target type for unknown option action")
+	}
+	return self
+}
+
+// AllowUnknown makes ParseArgs collect unrecognized options into UnknownArgs
+// instead of reporting an error. An unknown option is kept together with any
+// parameter joined to it (as in "--name=value" or "-xvalue"), and any short
+// options concatenated after an unknown short option are kept with it
+// unparsed. Since the parser can't know whether an unknown option takes a
+// parameter, a parameter in the following argument is treated as a non-option
+// argument. Returns self so that calls can be chained.
+func (self *OptionSet) AllowUnknown() *OptionSet {
+	self.allowUnknown = true
+	return self
+}
+
 // Section is equivalent to calling Add(Section(header)) on this OptionSet.
 // Returns self so that calls can be chained.
 func (self *OptionSet) Section(header string) *OptionSet {
@@ -318,13 +783,27 @@ func (self *OptionSet) Section(header string) *OptionSet {
 // Add a number of OptionDef entries to this option set. The target of each
 // entry is checked for validity, and the names are checked for redundant
 // definitions within this option set. If an error is detected, it is saved for
-// reporting later when ParseArgs is called.  The return value is self so that
-// calls to this method can be chained together.
+// reporting later when ParseArgs is called; use AddE or MustAdd to get it
+// immediately.  The return value is self so that calls to this method can be
+// chained together.
 func (self *OptionSet) Add(entries ...*OptionDef) *OptionSet {
 	// process each entry
 	for _, entry := range entries {
-		// add to in-order list
+		// remember the target's initial value for Reset
+		entry.captureDefault()
+
+		// positional argument definitions are kept separately
+		if entry.positional {
+			self.addPositional(entry)
+			continue
+		}
+
+		// add to in-order list; any compiled matcher is now out of date
 		self.list = append(self.list, entry)
+		if self.envPrefix != "" {
+			entry.envPrefix = self.envPrefix
+		}
+		self.matcher = nil
 
 		// check that target has a supported type
 		if !entry.isTargetOk() {
@@ -334,6 +813,14 @@ func (self *OptionSet) Add(entries ...*OptionDef) *OptionSet {
 			return self
 		}
 
+		// report any error from defining the option itself
+		if entry.setupError != nil {
+			if self.setupError == nil {
+				self.setupError = entry.setupError
+			}
+			return self
+		}
+
 		// process each name
 		for _, name := range strings.Split(entry.names, " ") {
 			if name != "" {
@@ -351,6 +838,157 @@ func (self *OptionSet) Add(entries ...*OptionDef) *OptionSet {
 	return self
 }
 
+// AddE adds entries to this option set in the same way as Add, but returns
+// any error found in them, such as an unsupported target type or a name that
+// is already defined, so that a programming mistake is reported where the
+// option is defined. If there is an error, none of the entries are added and
+// the error is not saved for ParseArgs, so the set is left as it was. An error
+// saved by an earlier call to Add is not returned.
+func (self *OptionSet) AddE(entries ...*OptionDef) error {
+	saved, listLen, posLen := self.setupError, len(self.list), len(self.positionals)
+	self.setupError = nil
+	self.Add(entries...)
+	err := self.setupError
+	if err != nil {
+		// roll back the entries added by this call
+		for _, entry := range self.list[listLen:] {
+			for _, name := range strings.Split(entry.names, " ") {
+				if self.index[name] == entry {
+					delete(self.index, name)
+				}
+			}
+		}
+		self.list, self.positionals = self.list[:listLen], self.positionals[:posLen]
+		self.matcher = nil
+	}
+	self.setupError = saved
+	return err
+}
+
+// MustAdd adds entries to this option set in the same way as Add, but panics
+// with the error if AddE would return one. Returns self so that calls can be
+// chained.
+func (self *OptionSet) MustAdd(entries ...*OptionDef) *OptionSet {
+	if err := self.AddE(entries...); err != nil {
+		panic(err)
+	}
+	return self
+}
+
+// Strict enables strict checking of the option definitions in this set. In
+// strict mode, every option must have help text, must appear after a section
+// header, and must declare a parameter name with the "=NAME; " help prefix if
+// it takes a parameter. A missing item is reported as a setup error when
+// ParseArgs is called. This is intended to enforce consistent help output in
+// large programs. Returns self so that calls can be chained.
+func (self *OptionSet) Strict() *OptionSet {
+	self.strict = true
+	return self
+}
+
+// Check the option definitions for the requirements of strict mode. Returns
+// an error for the first option found that doesn't meet them.
+func (self *OptionSet) checkStrict() error {
+	inSection := false
+	for _, def := range self.list {
+		if def.isSectionHeader() {
+			inSection = true
+			continue
+		}
+		valName, help := def.splitHelp()
+		switch {
+		case strings.TrimSpace(help) == "":
+			return fmt.Errorf("Option '%s' has no help text", def.formatOptionNames())
+		case def.takesParameter() && valName == "":
+			return fmt.Errorf("Option '%s' has no parameter name", def.formatOptionNames())
+		case !inSection:
+			return fmt.Errorf("Option '%s' is not in a section", def.formatOptionNames())
+		}
+	}
+	return nil
+}
+
+// Translate returns a new OptionSet presenting the options of this set under
+// different names, for example to rebrand a wrapper program without copying
+// its option definitions. Each key in names is an option name (without
+// dashes) in this set, and its value is the name to use instead; names not in
+// the map are kept, and a name mapped to "" is removed. The options in the
+// new set share their targets and help text with the options in this set, so
+// parsing with either set updates the same variables. Any settings made on
+// this set, such as ArgAction, constraints and loaded config files, are
+// carried over, and parses of the two sets exclude each other as with
+// ParseArgsWith. A ConfigFileOption or ConfigAuthOption of this set applies
+// to the new set instead. Name conflicts created by the translation are
+// reported when ParseArgs is called on the new set.
+func (self *OptionSet) Translate(names map[string]string) *OptionSet {
+	out := *self
+	out.list = nil
+	out.index = map[string]*OptionDef{}
+	copies := map[*OptionDef]*OptionDef{} // the option in the new set for each one in this set
+	for _, def := range self.list {
+		translated := *def
+		if !def.isSectionHeader() {
+			kept := []string{}
+			for _, name := range strings.Split(def.names, " ") {
+				if newName, ok := names[name]; ok {
+					name = newName
+				}
+				if name != "" {
+					kept = append(kept, name)
+				}
+			}
+			translated.names = strings.Join(kept, " ")
+		}
+		switch target := def.target.(type) {
+		case *configFile:
+			if target.set == self {
+				translated.target = &configFile{&out}
+			}
+		case *configAuthTarget:
+			if target.set == self {
+				translated.target = &configAuthTarget{&out}
+			}
+		}
+		copies[def] = &translated
+		out.Add(&translated)
+	}
+	// find the option in the new set for an option of this set, such as a
+	// positional argument, which is shared
+	find := func(def *OptionDef) *OptionDef {
+		if translated := copies[def]; translated != nil {
+			return translated
+		}
+		return def
+	}
+	out.sources = map[*OptionDef]ValueSource{}
+	for def, source := range self.sources {
+		out.sources[find(def)] = source
+	}
+	out.config = map[*OptionDef]*configValue{}
+	for def, value := range self.config {
+		out.config[find(def)] = value
+	}
+	out.deprecatedCount = map[*OptionDef]int{}
+	for def, count := range self.deprecatedCount {
+		out.deprecatedCount[find(def)] = count
+	}
+	out.constraints = nil
+	for _, c := range self.constraints {
+		renamed := []string{}
+		for _, name := range c.names {
+			if newName, ok := names[name]; ok && newName != "" {
+				name = newName
+			} else if def := copies[self.findName(name)]; ok && def != nil && def.names != "" {
+				// the name was removed; use another name of the option
+				name = strings.Fields(def.names)[0]
+			}
+			renamed = append(renamed, name)
+		}
+		out.constraints = append(out.constraints, &constraint{renamed, c.check})
+	}
+	return &out
+}
+
 // FormatOptionsHelp creates a list of lines of help output from the list of
 // OptionDef structures.  Generally, each line consists of the option
 // names followed by the help text, with the help text aligned in its own
@@ -360,95 +998,455 @@ func (self *OptionSet) Add(entries ...*OptionDef) *OptionSet {
 // the help text is output on the following line. The help text for any section
 // header entries are output as-is left justified.
 func (self *OptionSet) FormatOptionsHelp() []string {
-	out := []string{}
-	const padding = 20 // width of left column
+	return self.formatOptionsHelp(AutoHelp, false)
+}
 
-	// If autohelp is enabled, add a help entry for the help option
-	list := append([]*OptionDef{}, self.list...)
-	if AutoHelp && self.lookupDef("h") == nil && self.lookupDef("help") == nil {
-		list = append(list, &OptionDef{names: "h help", help: "Print this help message and exit"})
+// FormatBriefOptionsHelp creates a list of lines of help output in the same
+// way as FormatOptionsHelp, but leaves out the options marked with Advanced,
+// and any section headers that have no options left after them.
+func (self *OptionSet) FormatBriefOptionsHelp() []string {
+	return self.formatOptionsHelp(AutoHelp, true)
+}
+
+// Create the help lines for the options, including the automatic help option
+// if autoHelp is true and there is no other help option. If brief is true,
+// advanced options and empty sections are left out.
+func (self *OptionSet) formatOptionsHelp(autoHelp, brief bool) []string {
+	list := []*OptionDef{}
+	for _, def := range self.helpList(autoHelp) {
+		if def.hidden || (brief && def.advanced) {
+			continue
+		}
+		if brief && def.isSectionHeader() && len(list) > 0 && list[len(list)-1].isSectionHeader() {
+			// the previous section is empty
+			list = list[:len(list)-1]
+		}
+		list = append(list, def)
+	}
+	if brief && len(list) > 0 && list[len(list)-1].isSectionHeader() {
+		list = list[:len(list)-1]
+	}
+	// find the left column width for each option
+	paddings := make([]int, len(list))
+	start := 0
+	for i := 0; i <= len(list); i++ {
+		if i < len(list) && !list[i].isSectionHeader() {
+			continue
+		}
+		// end of a section; size it separately if enabled
+		padding := self.helpPadding()
+		if self.alignSections {
+			lefts := []string{}
+			for _, def := range list[start:i] {
+				lefts = append(lefts, self.optionHelpLeft(def))
+			}
+			padding = self.fitPadding(lefts)
+		}
+		for j := start; j < i; j++ {
+			paddings[j] = padding
+		}
+		start = i + 1
 	}
-	for _, def := range list {
+	out := []string{}
+	for i, def := range list {
 		if def.isSectionHeader() {
 			// Section separator comment
-			out = append(out, def.help)
+			out = append(out, self.colorHeader(def.help))
 		} else {
-			// Look for "=ARGNAME; help text", pull out ARGNAME if found
-			help := def.help
-			semi := strings.IndexByte(help, ';')
-			valName := ""
-			if semi > 0 && strings.HasPrefix(help, "=") {
-				valName = help[:semi]
-				help = strings.TrimLeft(help[semi+1:], " ")
-			}
-
-			// Format the option names followed by any ARGNAME
-			leftText := fmt.Sprintf("%-*s", padding, "  "+def.formatOptionNames()+valName)
-			if strings.HasSuffix(leftText, " ") {
-				// Fits within the left column, add the help text
-				out = append(out, leftText+help)
-			} else {
-				// Doesn't fit, output on separate lines
-				out = append(out, leftText)
-				out = append(out, strings.Repeat(" ", padding)+help)
+			_, help := def.splitHelp()
+			out = append(out, self.formatHelpEntry(self.optionHelpLeft(def), def.fullHelp(help, self.showDefaults), paddings[i])...)
+		}
+	}
+	return out
+}
+
+// Return the options to show in help output, with an entry for the automatic
+// help option added if autoHelp is true and there is no other help option.
+func (self *OptionSet) helpList(autoHelp bool) []*OptionDef {
+	list := append([]*OptionDef{}, self.list...)
+	if autoHelp && self.lookupDef("h") == nil && self.lookupDef("help") == nil {
+		list = append(list, &OptionDef{names: "h help", help: "Print this help message and exit"})
+	}
+	if autoHelp && self.autoVersion() {
+		list = append(list, &OptionDef{names: "V version", help: "Print the version and exit"})
+	}
+	if self.sortHelp {
+		// sort the options between each pair of section headers
+		start := 0
+		for i := 0; i <= len(list); i++ {
+			if i == len(list) || list[i].isSectionHeader() {
+				section := list[start:i]
+				sort.SliceStable(section, func(a, b int) bool {
+					return section[a].canonicalName() < section[b].canonicalName()
+				})
+				start = i + 1
 			}
 		}
 	}
+	// move deprecated options to the end of their sections
+	start := 0
+	for i := 0; i <= len(list); i++ {
+		if i == len(list) || list[i].isSectionHeader() {
+			section := list[start:i]
+			sort.SliceStable(section, func(a, b int) bool {
+				return section[a].deprecated == "" && section[b].deprecated != ""
+			})
+			start = i + 1
+		}
+	}
+	return list
+}
+
+// ShowDefaults makes the help output of this set show the default value of
+// each option and positional argument with a variable target, as in
+// "(default: 3)" at the end of the help text. The default is the value the
+// target variable held when the option was added to the set. Zero values,
+// such as 0, false, "" or an empty list, are not shown. Returns self so that
+// calls can be chained.
+func (self *OptionSet) ShowDefaults() *OptionSet {
+	self.showDefaults = true
+	return self
+}
+
+// Advanced marks this option as one that is only of interest to advanced
+// users. If any option in a set is advanced, the automatic "-h" option shows
+// brief help without the advanced options, while "--help" shows them all.
+// Returns self so that calls can be chained.
+func (self *OptionDef) Advanced() *OptionDef {
+	self.advanced = true
+	return self
+}
+
+// Check whether any option in the set is marked with Advanced.
+func (self *OptionSet) hasAdvancedOptions() bool {
+	for _, def := range self.list {
+		if def.advanced {
+			return true
+		}
+	}
+	return false
+}
+
+// SortHelp makes the help output list the options of this set in
+// alphabetical order of their first long names (or short names, for options
+// with no long name) within each section, rather than in the order they were
+// defined. Section headers stay in place. Returns self so that calls can be
+// chained.
+func (self *OptionSet) SortHelp() *OptionSet {
+	self.sortHelp = true
+	return self
+}
+
+// Return the left column text of the help entry for def: the option names
+// followed by any parameter name.
+func (self *OptionSet) optionHelpLeft(def *OptionDef) string {
+	valName, _ := def.splitHelp()
+	if def.optionalParam && valName != "" {
+		valName = "[" + valName + "]"
+	}
+	return def.formatNames(self.isNegatable(def)) + valName
+}
+
+// HelpIndent sets the number of spaces before each entry in the help output
+// of this set. The default is 2. Returns self so that calls can be chained.
+func (self *OptionSet) HelpIndent(indent int) *OptionSet {
+	self.helpIndent = indent
+	return self
+}
+
+// HelpColumn sets the width of the left column of the help output of this
+// set, which holds the option names and any indent. The help text starts in
+// the column after it; an entry whose names don't fit is shown with its help
+// text on the following line. A width of zero selects the default of 20. A
+// width less than zero sizes the column automatically, so that the longest
+// entry of the options, arguments and commands fits with two spaces to spare.
+// Returns self so that calls can be chained.
+func (self *OptionSet) HelpColumn(width int) *OptionSet {
+	self.helpColumn = width
+	return self
+}
+
+// Return the width of the left column of help output.
+func (self *OptionSet) helpPadding() int {
+	switch {
+	case self.helpColumn > 0:
+		return self.helpColumn
+	case self.helpColumn == 0:
+		return 20
+	}
+	// automatic; fit the longest left column text
+	lefts := []string{}
+	for _, def := range self.helpList(AutoHelp) {
+		if !def.hidden && !def.isSectionHeader() {
+			lefts = append(lefts, self.optionHelpLeft(def))
+		}
+	}
+	for _, def := range self.argumentDefs() {
+		lefts = append(lefts, def.positionalName())
+	}
+	for _, cmd := range self.commands {
+		if !cmd.isSectionHeader() {
+			lefts = append(lefts, cmd.Name())
+		}
+	}
+	if self.autoHelpCommand() {
+		lefts = append(lefts, "help")
+	}
+	return self.fitPadding(lefts)
+}
+
+// Return the left column width that fits the longest of lefts, with the
+// indent and two spaces to spare.
+func (self *OptionSet) fitPadding(lefts []string) int {
+	width := 0
+	for _, left := range lefts {
+		if w := displayWidth(left); w > width {
+			width = w
+		}
+	}
+	return self.helpIndent + width + 2
+}
+
+// AlignSections sizes the left column of the help output of this set
+// automatically for each section of options, and for the arguments and the
+// commands, separately. A section of short options then isn't forced to the
+// width needed by the long option names of another. This overrides the width
+// set with HelpColumn. Returns self so that calls can be chained.
+func (self *OptionSet) AlignSections() *OptionSet {
+	self.alignSections = true
+	return self
+}
+
+// WrapWidth makes the help output of this set wrap the help text of each
+// entry so that lines are at most width columns wide where possible, with
+// the continuation lines aligned in the help column. Words longer than the
+// space available are not broken. A width of zero, the default, turns
+// wrapping off. Returns self so that calls can be chained.
+func (self *OptionSet) WrapWidth(width int) *OptionSet {
+	self.wrapWidth = width
+	return self
+}
+
+// Split text into lines of at most width columns, breaking at spaces. A
+// width less than one means no limit. Leading spaces are kept on the first
+// line only.
+func wrapText(text string, width int) []string {
+	if width < 1 || displayWidth(text) <= width {
+		return []string{text}
+	}
+	lines := []string{}
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case displayWidth(line)+1+displayWidth(word) <= width:
+			line += " " + word
+		default:
+			lines = append(lines, line)
+			line = word
+		}
+	}
+	return append(lines, line)
+}
+
+// Format one entry of help output, with the left text indented and the help
+// text aligned in the column after it. If the left text doesn't fit within
+// the left column, the help text is output on the following line. Any further
+// lines of the help text are output aligned with the first, keeping blank
+// lines between paragraphs.
+func (self *OptionSet) formatHelpEntry(left, help string, padding int) []string {
+	lines := []string{}
+	for _, line := range strings.Split(help, "\n") {
+		lines = append(lines, wrapText(line, self.wrapWidth-padding)...)
+	}
+	out := []string{}
+	leftText := strings.Repeat(" ", self.helpIndent) + self.colorHelpLeft(left)
+	if fill := padding - self.helpIndent - displayWidth(left); fill > 0 {
+		// Fits within the left column, add the help text
+		out = append(out, leftText+strings.Repeat(" ", fill)+lines[0])
+	} else {
+		// Doesn't fit, output on separate lines
+		out = append(out, leftText, strings.Repeat(" ", padding)+lines[0])
+	}
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			out = append(out, "")
+		} else {
+			out = append(out, strings.Repeat(" ", padding)+strings.TrimSpace(line))
+		}
+	}
 	return out
 }
 
+// Return the help text with any annotations from helpNotes added.
+func (self *OptionDef) fullHelp(help string, showDefault bool) string {
+	if notes := self.helpNotes(showDefault); len(notes) > 0 {
+		help = strings.TrimRight(help+" "+strings.Join(notes, " "), " ")
+	}
+	return help
+}
+
+// Look for "=ARGNAME; help text" in the help string. If found, return
+// "=ARGNAME" and the help text following it. Otherwise return an empty
+// ARGNAME and the help string unchanged. A name set with Metavar takes the
+// place of any ARGNAME in the help string.
+func (self *OptionDef) splitHelp() (valName, help string) {
+	help = self.help
+	semi := strings.IndexByte(help, ';')
+	if semi > 0 && strings.HasPrefix(help, "=") {
+		valName = help[:semi]
+		help = strings.TrimLeft(help[semi+1:], " ")
+	}
+	if self.metavar != "" {
+		valName = "=" + self.metavar
+	}
+	return valName, help
+}
+
+// Tag attaches badges such as "experimental" or "since v2.1" to this option.
+// They are shown in brackets at the end of the option's help text, as in
+// "[experimental]", and are available to help templates and other
+// documentation generators through HelpData. Returns self so that calls can
+// be chained.
+func (self *OptionDef) Tag(tags ...string) *OptionDef {
+	self.tags = append(self.tags, tags...)
+	return self
+}
+
+// Tags returns the badges attached to this option with Tag.
+func (self *OptionDef) Tags() []string {
+	return self.tags
+}
+
+// Metavar sets the name shown for this option's parameter in help output, as
+// in "--output=FILE". This is an alternative to starting the help string with
+// "=FILE; ", and takes precedence over it. Returns self so that calls can be
+// chained.
+func (self *OptionDef) Metavar(name string) *OptionDef {
+	self.metavar = name
+	return self
+}
+
+// Return any annotations to be added to the end of this option's help text,
+// such as where its default value came from.
+func (self *OptionDef) helpNotes(showDefault bool) []string {
+	notes := []string{}
+	if self.defaultEnv != "" {
+		notes = append(notes, fmt.Sprintf("(default from $%s)", self.defaultEnv))
+	} else if text := self.formatDefault(); showDefault && text != "" {
+		notes = append(notes, fmt.Sprintf("(default: %s)", text))
+	}
+	if chooser, ok := self.target.(Chooser); ok {
+		notes = append(notes, fmt.Sprintf("(one of: %s)", strings.Join(chooser.Choices(), ", ")))
+	}
+	if self.required {
+		notes = append(notes, "(required)")
+	}
+	if self.deprecated != "" {
+		notes = append(notes, fmt.Sprintf("(deprecated: %s)", self.deprecated))
+	}
+	if keys := self.allEnvKeys(); len(keys) > 0 {
+		notes = append(notes, fmt.Sprintf("[env: %s]", strings.Join(keys, ", ")))
+	}
+	for _, tag := range self.tags {
+		notes = append(notes, "["+tag+"]")
+	}
+	return notes
+}
+
+// Return the default value captured when the option was added, formatted for
+// help output. Returns "" if there is no default value, or if it is the zero
+// value of its type, such as 0, false or an empty list.
+func (self *OptionDef) formatDefault() string {
+	switch value := self.defaultValue.(type) {
+	case nil:
+		return ""
+	case []string:
+		return strings.Join(value, ", ")
+	default:
+		if reflect.ValueOf(value).IsZero() {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+}
+
 // Set the target in the OptionDef with the given value. If the target is a
 // setter function, call it. Otherwise, in most cases convert the string to the
 // type of the target and set it. For the case of bool, the value is ignored
 // and the target is set to true. In the case of a string list, append the
-// value to the list. Returns an error if a conversion fails or the
-// setter function returns an error.
-func (self *OptionDef) set(value string) error {
+// value to the list; if Unique dropped the value instead, a warning is kept
+// for the parse p. Unless p is nil, the value is also recorded in p. Returns
+// an error if a conversion fails or the setter function returns an error.
+func (self *OptionDef) set(value string, p *parser) error {
 	var err error
 	var i int64
 	var u uint64
 	var f float64
 
+	value = self.normalize(value)
 	switch target := self.target.(type) {
 	// setter that takes a parameter and never has errors
 	case func(string):
-		target(value)
+		if err = self.check(value, nil); err == nil {
+			target(value)
+		}
 	// setter that takes no parameter and never has errors
 	case func():
 		target()
 	// setter that takes a parameter and may have errors
 	case func(string) error:
-		err = target(value)
+		if err = self.check(value, nil); err == nil {
+			err = target(value)
+		}
 	// setter that takes no parameter and may have errors
 	case func() error:
 		err = target()
+	// config file named on the command line; see ConfigFileOption
+	case *configFile:
+		if err = self.check(value, nil); err == nil {
+			err = target.load(value, self.base(), p)
+		}
+	// authorization for config file URLs; see ConfigAuthOption
+	case *configAuthTarget:
+		if err = self.check(value, nil); err == nil {
+			err = target.record(value, p)
+		}
+	// value that handles its own parameter
+	case Setter:
+		if err = self.check(value, nil); err == nil {
+			err = target.Set(value)
+		}
 	// string target: no conversion
 	case *string:
-		*target = value
+		if err = self.check(value, nil); err == nil {
+			*target = value
+		}
 	// numeric targets: attempt to convert to number
 	case *uint:
 		u, err = strconv.ParseUint(value, 0, 0)
-		if err == nil {
+		if err = self.check(uint(u), err); err == nil {
 			*target = uint(u)
 		}
 	case *uint64:
 		u, err = strconv.ParseUint(value, 0, 64)
-		if err == nil {
+		if err = self.check(u, err); err == nil {
 			*target = u
 		}
 	case *int:
 		i, err = strconv.ParseInt(value, 0, 0)
-		if err == nil {
+		if err = self.check(int(i), err); err == nil {
 			*target = int(i)
 		}
 	case *int64:
 		i, err = strconv.ParseInt(value, 0, 64)
-		if err == nil {
+		if err = self.check(i, err); err == nil {
 			*target = i
 		}
 	case *float64:
 		f, err = strconv.ParseFloat(value, 64)
-		if err == nil {
+		if err = self.check(f, err); err == nil {
 			*target = f
 		}
 	// bool target: set it to true
@@ -456,20 +1454,184 @@ func (self *OptionDef) set(value string) error {
 		*target = true
 	// string slice target: append to slice
 	case *[]string:
-		*target = append(*target, value)
+		if err = self.check(value, nil); err == nil && self.dedupe && containsString(*target, value) {
+			if p != nil {
+				p.dropped = append(p.dropped, fmt.Sprintf("Repeated value '%s' for %s ignored", value, self.displayName()))
+			}
+			return nil
+		} else if err == nil {
+			*target = append(*target, value)
+		}
 	default:
 		err = fmt.Errorf("Unsupported type given as target to to ParseArgs for option '%s'", self.formatOptionNames())
 	}
+	if err == nil && p != nil {
+		p.values[self.base()] = append(p.values[self.base()], value)
+	}
 	return err
 }
 
+// Set the target from an explicitly given value, such as the "no" in
+// "--flag=no" or an environment variable. This is the same as set, except
+// that bool targets are set to the parsed value instead of true.
+func (self *OptionDef) setExplicit(value string, p *parser) error {
+	if target, ok := self.target.(*bool); ok {
+		b, err := parseBool(value)
+		if err == nil {
+			*target = b
+		}
+		return err
+	}
+	return self.set(value, p)
+}
+
+// Convert a string to a bool. In addition to the values accepted by
+// strconv.ParseBool, "yes", "no", "on" and "off" are accepted in any case.
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "yes", "on":
+		return true, nil
+	case "no", "off":
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("Invalid boolean value '%s'", value)
+	}
+	return b, nil
+}
+
 // Search the given list of OptionDef structures to find one with a name
 // matching the given name.  If any of an option's names matches name, then
 // return a pointer to that option. If the entry kind is for the non-option
 // argument handler or for the undefined option handler, then name is ignored.
 // If no matching OptionDef is found, return nil.
 func (self *OptionSet) lookupDef(name string) *OptionDef {
-	return self.index[name]
+	if def := self.findName(name); def != nil {
+		return def
+	}
+	// check for an automatic negation of a boolean option
+	if strings.HasPrefix(name, "no-") {
+		if def := self.findName(name[3:]); def != nil && len(name) > 4 && self.isNegatable(def) {
+			return &OptionDef{names: name, target: FlagResetOption(def.target.(*bool)), negates: def}
+		}
+	}
+	return nil
+}
+
+// Check if automatic negations are enabled and def is a boolean option.
+func (self *OptionSet) isNegatable(def *OptionDef) bool {
+	return self.negations && def.isBool()
+}
+
+// Lookup returns the OptionDef in this set that has the given name (without
+// any leading dashes), or nil if there is no such option.
+func (self *OptionSet) Lookup(name string) *OptionDef {
+	return self.lookupDef(name)
+}
+
+// Values returns the current values of the variable targets of the options in
+// this set, keyed by each option's canonical name: its first long name, or its
+// first name if it has no long names. Options whose targets are setter
+// functions are not included. Slice values are copies, so later parsing does
+// not change the returned map. This allows clients that can't bind Go
+// variables ahead of time to consume the results of a parse dynamically.
+func (self *OptionSet) Values() map[string]interface{} {
+	values := map[string]interface{}{}
+	for _, def := range append(self.list, self.positionals...) {
+		if def.isSectionHeader() {
+			continue
+		}
+		switch target := def.target.(type) {
+		case *[]string:
+			values[def.canonicalName()] = append([]string{}, *target...)
+		case *string, *uint, *uint64, *int, *int64, *float64, *bool:
+			values[def.canonicalName()] = reflect.ValueOf(target).Elem().Interface()
+		}
+	}
+	return values
+}
+
+// Reset restores every variable target of the options and positional
+// arguments in this set to the value it held when the option was added to the
+// set, and forgets the value sources recorded by the last parse and any
+// authorization set for config file URLs with ConfigAuthOption. This allows
+// an OptionSet to be reused for several calls to ParseArgs, for example in
+// tests or long-running programs. Targets that are setter functions are not
+// affected.
+func (self *OptionSet) Reset() *OptionSet {
+	for _, def := range append(self.list, self.positionals...) {
+		def.restoreDefault()
+	}
+	self.sources = map[*OptionDef]ValueSource{}
+	self.configAuth = ""
+	return self
+}
+
+// ParseArgsWith parses args in the same way as Parse and calls use with the
+// ParseResult and the error, while holding a lock on this set, so that one
+// OptionSet can be shared by goroutines parsing different command lines,
+// such as a server interpreting commands from its clients. Before parsing,
+// the targets of this set and its commands are reset as with Reset, so no
+// values are left over from a previous parse. As with Parse, the results are
+// only returned in the ParseResult, and Args and UnknownArgs are not set.
+//
+// This serializes the parses rather than running them in parallel, since
+// they share the targets of the options. So use must finish with the values
+// of the targets before returning, and must not call ParseArgs or
+// ParseArgsWith on this set. Other goroutines that call ParseArgs or its
+// variants on this set wait until use returns. To parse in parallel, give
+// each goroutine its own OptionSet. Use ErrorHandling to get errors back
+// instead of exiting. Returns the error returned by use.
+func (self *OptionSet) ParseArgsWith(args []string, use func(result *ParseResult, err error) error) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.resetAll()
+	p, _, err := self.parse(args, parseMode{locked: true, detached: true})
+	return use(p.result(), err)
+}
+
+// Reset this set and the sets of its commands.
+func (self *OptionSet) resetAll() {
+	self.Reset()
+	for _, cmd := range self.commands {
+		if cmd.set != nil {
+			cmd.set.resetAll()
+		}
+	}
+}
+
+// Save a copy of the current value of a variable target so that it can be
+// restored by restoreDefault.
+func (self *OptionDef) captureDefault() {
+	switch target := self.target.(type) {
+	case *[]string:
+		if *target != nil {
+			self.defaultValue = append([]string{}, *target...)
+		} else {
+			self.defaultValue = []string(nil)
+		}
+	case *string, *uint, *uint64, *int, *int64, *float64, *bool:
+		self.defaultValue = reflect.ValueOf(target).Elem().Interface()
+	}
+}
+
+// Restore a variable target to the value saved by captureDefault.
+func (self *OptionDef) restoreDefault() {
+	if self.defaultValue == nil {
+		return
+	}
+	value := self.defaultValue
+	if list, ok := value.([]string); ok && list != nil {
+		value = append([]string{}, list...)
+	}
+	reflect.ValueOf(self.target).Elem().Set(reflect.ValueOf(value))
+}
+
+// Target returns the target of this option, i.e. the variable pointer or
+// setter function that was given when the option was defined.
+func (self *OptionDef) Target() interface{} {
+	return self.target
 }
 
 // ParseArgs parses the given command line arguments in args according to these
@@ -480,23 +1642,225 @@ func (self *OptionSet) lookupDef(name string) *OptionDef {
 // error is encountered, parsing stops and a non-nil error is also returned. If
 // an error is returned, only some of the side effects may have been performed,
 // and the returned argument list may be incomplete.  A copy of the returned
-// non-option argument list is also stored in the global variable Args.
+// non-option argument list is also stored in the global variable Args. If
+// AllowUnknown was called, unrecognized options are stored in UnknownArgs.
+// If the automatic help option or help command is given, the usage message is
+// displayed and ErrHelp is returned without calling OnError, so the program
+// can exit in its own way, normally with a zero status; the automatic version
+// option likewise displays the version and returns ErrVersion:
+//
+//	if _, err := set.ParseArgs(nil); errors.Is(err, miniflags.ErrHelp) {
+//		return
+//	}
+//
+// See also ParseKnownArgs.
+//
+// A long option's name ends at the first '=' in the argument, and everything
+// after that '=' is the parameter, so "--define=key=value" gives the parameter
+// "key=value" and "--define==x" gives "=x". A parameter joined to a short
+// option is everything after the option letter, except that a single leading
+// '=' is removed as a delimiter: "-Dkey=value" and "-D=key=value" both give
+// "key=value", and "-D==x" gives "=x". RawShortParameters disables removing
+// the delimiter. A parameter given in a separate argument is never changed.
 func (self *OptionSet) ParseArgs(args []string) ([]string, error) {
+	_, rest, err := self.parse(args, parseMode{})
+	return rest, err
+}
+
+// ParseKnownArgs parses the options in args that are defined in this set, in
+// the same way as ParseArgs, but does not report an error for unknown options.
+// Instead, it returns all the arguments that it didn't consume, in their
+// original order: unknown options and non-option arguments (including any
+// parameters of unknown options, since these can't be distinguished from
+// non-option arguments), plus any terminator and the arguments after it. Known
+// targets are set as usual. This lets a front end strip its own options and
+// hand the rest to another parser. The returned list is also stored in Args.
+func (self *OptionSet) ParseKnownArgs(args []string) ([]string, error) {
+	_, rest, err := self.parse(args, parseMode{passUnknown: true})
+	return rest, err
+}
+
+// ParseOnly parses args for only the named options (without dashes), ignoring
+// everything else, so that a few options can be handled before the full parse
+// is done. For example, a program can get the location of a config file from
+// a "--config" option, load the file, and then call ParseArgs to parse the
+// complete command line. Other defined options are skipped along with their
+// parameters, without performing their actions; unknown options, non-option
+// arguments and errors caused by skipped options are ignored. Environment
+// variables are applied only to the named options, and constraints are not
+// checked. Args is not changed. An undefined name is reported as an error.
+func (self *OptionSet) ParseOnly(args []string, names ...string) error {
+	only := map[*OptionDef]bool{}
+	for _, name := range names {
+		def := self.findName(name)
+		if def == nil {
+			err := fmt.Errorf("Unknown option name '%s' given to ParseOnly", name)
+			self.handleError(err)
+			return err
+		}
+		only[def] = true
+	}
+	_, _, err := self.parse(args, parseMode{only: only})
+	return err
+}
+
+// Settings for a single parse that vary between the ParseArgs variants
+type parseMode struct {
+	passUnknown  bool                // return unknown options and terminators in place with the arguments
+	only         map[*OptionDef]bool // if not nil, perform only these options and ignore anything else
+	returnErrors bool                // return errors without calling OnError or exiting the program
+	errs         *Errors             // problems collected so far when AllErrors is set
+	offset       int                 // the index of the first argument in the whole command line
+	locked       bool                // the lock of the set being parsed is already held
+	detached     bool                // leave the set, Args and UnknownArgs alone; the results are returned otherwise
+	parent       *parser             // the parse of the set that this one is a command of, if any
+}
+
+// The state of a single parse of a set, kept apart from the set so that a
+// parse changes nothing but the targets of the options. The parse of a
+// selected command has a parser of its own.
+type parser struct {
+	set         *OptionSet
+	parent      *parser                     // The parse of the set that this one is a command of, if any
+	sub         *parser                     // The parse of the selected command, if any
+	sources     map[*OptionDef]ValueSource  // Where each option got its value
+	counts      map[*OptionDef]int          // The number of times each option was given
+	values      map[*OptionDef][]string     // The values set for each option, in order
+	config      map[*OptionDef]*configValue // Values loaded from config files, including those named by options
+	configFiles []namedConfig               // Config files named by options, read after the arguments
+	configAuth  *string                     // Authorization for config file URLs given by an option, if any
+	dropped     []string                    // Warnings for values dropped by Unique, reported at the end
+	warnings    []string                    // The warnings issued during the parse
+	args        []string                    // The non-option arguments found
+	unknown     []string                    // The unknown options passed through
+	terminated  []string                    // The arguments after the terminator, if any
+	command     *CommandDef                 // The selected command, if any
+}
+
+// Return a parser for set, starting with the config values loaded into the
+// set before the parse.
+func newParser(set *OptionSet, parent *parser) *parser {
+	p := &parser{set: set, parent: parent, sources: map[*OptionDef]ValueSource{},
+		counts: map[*OptionDef]int{}, values: map[*OptionDef][]string{}, config: map[*OptionDef]*configValue{}}
+	for def, value := range set.config {
+		p.config[def] = value
+	}
+	return p
+}
+
+// Return the parser of set, which is this one or one that this is the parse
+// of a command of, or nil if set is not being parsed.
+func (self *parser) forSet(set *OptionSet) *parser {
+	for p := self; p != nil; p = p.parent {
+		if p.set == set {
+			return p
+		}
+	}
+	return nil
+}
+
+// Report err for set through OnError, unless errors are only returned in
+// this mode. Any problems collected so far are reported along with it.
+func (self parseMode) report(set *OptionSet, err error) {
+	if self.errs != nil && len(*self.errs) > 0 {
+		err = append(*self.errs, err)
+	}
+	if !self.returnErrors {
+		set.onErrorFunc(err)
+	}
+}
+
+// Return an option that is parsed in the same way as def but has no effect,
+// for skipping options during ParseOnly.
+func skippedDef(def *OptionDef) *OptionDef {
+	skip := &OptionDef{names: def.names, target: func() {}}
+	switch {
+	case def.takesParameter():
+		skip.target = func(string) {}
+	case def.isBool():
+		skip.target = new(bool)
+	}
+	return skip
+}
+
+// Parse args according to mode; this does the work for ParseArgs and its
+// variants.
+func (self *OptionSet) parse(args []string, mode parseMode) (p *parser, _ []string, err error) {
 	// default to args from os if nil
 	if args == nil {
 		args = os.Args[1:]
 	}
+	if !mode.locked {
+		// commands are parsed under the lock of the outermost set
+		self.mu.Lock()
+		defer self.mu.Unlock()
+		mode.locked = true
+	}
+	p = newParser(self, mode.parent)
+	mode.parent = p
+	if !mode.detached {
+		// keep the results for Source, SelectedCommand and CommandPath
+		defer func() { self.sources, self.command = p.sources, p.command }()
+	}
+	// report the values dropped by Unique during this parse, however it ends
+	defer func() {
+		for _, message := range p.dropped {
+			p.warnQuietly(message)
+		}
+	}()
+	if self.errorHandling != ExitOnError && !mode.returnErrors {
+		mode.returnErrors = true
+		if self.errorHandling == PanicOnError {
+			defer self.panicOnError(&err)
+		}
+	}
 
 	// If there was an error detected during setup, report it now and quit
-	if self.setupError != nil {
-		OnError(self, self.setupError)
-		return nil, self.setupError
+	setupError := self.setupError
+	if setupError == nil && self.strict {
+		setupError = self.checkStrict()
+	}
+	if setupError == nil {
+		setupError = self.checkConstraintNames()
+	}
+	if setupError != nil {
+		mode.report(self, setupError)
+		return p, nil, setupError
 	}
 
-	var err error
+	var remembered *configBatch // the values saved by RememberValues, if any
+	if self.statePath != "" {
+		if remembered, err = self.loadState(); err != nil {
+			mode.report(self, err)
+			return p, nil, err
+		}
+	}
+	if self.responseFiles || self.stdinToken != "" {
+		if args, err = self.expandArgs(args); err != nil {
+			mode.report(self, err)
+			return p, nil, err
+		}
+	}
 	argsOut := []string{}
+	unknownOut := []string{}
+	var errs Errors // problems collected when AllErrors is set
+	mode.errs = &errs
 	moreShorts := ""    // for a short option, any chars found after the first
 	terminated := false // the "--" terminator has been encountered
+	terminator := -1    // the index of the terminator in argsOut, if any
+
+	var context *OptionSet // the option context started by the last argument, if any
+	posArgs := []string{}  // arguments for the positional argument definitions
+	posCapacity := self.positionalCapacity()
+	// find an option by name, trying the current context first
+	lookup := func(name string) *OptionDef {
+		if context != nil {
+			if def := context.lookupDef(name); def != nil {
+				return def
+			}
+		}
+		return self.lookupInherited(name)
+	}
 	i := 0
 argLoop:
 	// parse each argument
@@ -505,6 +1869,7 @@ argLoop:
 		var name string      // the name of this option
 		var arg string       // the current argument
 		var def *OptionDef   // the relevant option definition for this arg, if any
+		short := false       // the option was given in the short form
 
 		if moreShorts != "" {
 			// we have more short options that were concatenated with previous short option; use them
@@ -517,9 +1882,18 @@ argLoop:
 
 		// take action based on dashes
 		switch {
-		case !terminated && arg == "--":
+		case !terminated && self.isTerminator(arg):
 			// end of options marker
 			terminated = true
+			p.terminated = append([]string{}, args[i+1:]...)
+			if mode.passUnknown {
+				argsOut = append(argsOut, args[i:]...)
+				break argLoop
+			}
+			// keep it in place with the arguments
+			terminator = len(argsOut)
+			argsOut = append(argsOut, arg)
+			i++
 			continue argLoop
 		case !terminated && strings.HasPrefix(arg, "--"):
 			// long option name; look for a '=' delimiter
@@ -532,14 +1906,60 @@ argLoop:
 				// no '=', just get name
 				name = arg[2:]
 			}
-			def = self.lookupDef(name)
+			def = lookup(name)
 		case !terminated && len(arg) > 1 && strings.HasPrefix(arg, "-"):
 			// short option, any parameter or more shorts are after 1-character name
 			parameter = arg[2:]
 			name = arg[1:2]
-			def = self.lookupDef(name)
+			short = true
+			def = lookup(name)
 		default:
 			// non-option argument (includes "-")
+			if mode.only != nil {
+				// only parsing selected options
+				i++
+				continue argLoop
+			}
+			if len(self.commands) > 0 {
+				// the first argument selects a command, which parses the rest
+				cmd := self.findCommand(arg)
+				if cmd == nil && arg == "help" && self.autoHelpCommand() {
+					// built-in help command; show the usage of the named command
+					var set *OptionSet
+					if set, err = self.helpCommandSet(args[i+1:]); err != nil {
+						break argLoop
+					}
+					set.showUsage()
+					err = ErrHelp
+					break argLoop
+				}
+				if cmd == nil {
+					err = self.unknownCommandError(arg)
+					break argLoop
+				}
+				var rest []string
+				cmdMode := mode
+				cmdMode.offset += i + 1
+				if rest, err = p.parseCommand(cmd, args[i+1:], cmdMode); err != nil {
+					// already reported by the command's set
+					return p, rest, err
+				}
+				argsOut = append(argsOut, rest...)
+				break argLoop
+			}
+			if self.argContext != nil {
+				// start a new option context for the arguments that follow
+				if context = self.argContext(arg); context != nil && context.setupError != nil {
+					err = context.setupError
+					break argLoop
+				}
+			}
+			if posCapacity < 0 || len(posArgs) < posCapacity {
+				// save the argument for the positional argument definitions
+				posArgs = append(posArgs, arg)
+				i++
+				continue argLoop
+			}
 			if self.argAction == nil {
 				// Normal case; add arg to arguments list and go on
 				i++
@@ -553,37 +1973,95 @@ argLoop:
 			}
 		}
 
+		if def == nil && mode.only != nil {
+			// only parsing selected options; ignore unknown ones
+			i++
+			continue argLoop
+		}
 		if def == nil {
 			// no definition found, check if automatic help should be shown
 			if AutoHelp && (name == "h" || name == "help") &&
 				self.lookupDef("h") == nil && self.lookupDef("help") == nil {
-				Usage(self)
-				os.Exit(0)
+				if name == "h" && self.hasAdvancedOptions() {
+					BriefUsage(self)
+				} else {
+					self.showUsage()
+				}
+				err = ErrHelp
+				break argLoop
+			}
+			if (name == "V" || name == "version") && self.autoVersion() {
+				self.Emit(Version)
+				err = ErrVersion
+				break argLoop
+			}
+			if self.unknownAct != nil {
+				// custom action for unknown options; give it the raw token
+				if err = self.unknownAct.set(arg, p); err != nil {
+					err = &ErrBadValue{Option: arg, Value: arg, Source: ValueSource{SourceCommandLine, arg}, Err: err}
+					break argLoop
+				}
+				i++
+				continue argLoop
+			}
+			if mode.passUnknown {
+				// return the unknown option in place with the arguments
+				argsOut = append(argsOut, arg)
+				i++
+				continue argLoop
+			}
+			if self.allowUnknown {
+				// pass the unknown option through untouched
+				unknownOut = append(unknownOut, arg)
+				i++
+				continue argLoop
 			}
 			// report not found error
-			err = fmt.Errorf("Unknown option '%s'", arg)
-			OnError(self, err)
+			err = &ErrUnknownOption{Name: arg}
+			break argLoop
+		}
+
+		skipped := mode.only != nil && !mode.only[def.base()]
+		if skipped {
+			// only parsing selected options; parse this one without effect
+			def = skippedDef(def)
+		}
+
+		// check that the option hasn't been given too many times
+		if max := def.base().maxCount; max > 0 && p.counts[def.base()] >= max {
+			if max == 1 {
+				err = fmt.Errorf("Option '%s' given more than once", def.base().displayName())
+			} else {
+				err = fmt.Errorf("Option '%s' given more than %d times", def.base().displayName(), max)
+			}
 			break argLoop
 		}
 
 		// option definition was found; process it
 		if def.takesParameter() {
 			// option has a parameter
-			if parameter == "" {
+			if parameter == "" && def.optionalParam {
+				// optional parameter was left out; use the implicit value
+				parameter = def.implicit
+			} else if parameter == "" {
 				// parameter was not concatenated with option, get the next command line arg as parameter
-				if i >= len(args)-1 {
-					err = fmt.Errorf("Expected a parameter after option '%s'", arg)
-					OnError(self, err)
+				if i >= len(args)-1 && skipped {
+					break argLoop
+				} else if i >= len(args)-1 {
+					err = &ErrMissingParameter{Option: arg}
 					break argLoop
 				}
 				i++
 				parameter = args[i]
-			} else if strings.HasPrefix(parameter, "=") {
+			} else if strings.HasPrefix(parameter, "=") && !(short && self.rawShortParams) {
 				// parameter was concatenated with option; strip any '=' delimiter
 				parameter = parameter[1:]
 			}
 			// use the parameter to perform the specified action
-			err = def.set(parameter)
+			err = def.set(parameter, p)
+		} else if def.isBool() && strings.HasPrefix(parameter, "=") {
+			// boolean option with an explicit value joined by '='
+			err = def.setExplicit(parameter[1:], p)
 		} else {
 			// option has no parameter
 			if parameter != "" {
@@ -591,20 +2069,96 @@ argLoop:
 				moreShorts = "-" + parameter
 			}
 			// perform the specified action
-			err = def.set("")
+			err = def.set("", p)
 		}
 		// check for an error with the action
 		if err != nil {
-			err = fmt.Errorf("Error with command line option '%s': %v", arg, err)
-			OnError(self, err)
+			err = &ErrBadValue{Option: def.base().displayName(), Value: parameter,
+				Source: ValueSource{SourceCommandLine, arg}, Err: err}
+		}
+		if err != nil && self.allErrors {
+			// collect the error and go on to the next argument; the option
+			// still counts as given, so that a required one isn't also
+			// reported as missing
+			errs = append(errs, &ParseError{Index: mode.offset + i, Token: args[i], Err: err})
+			err = nil
+			p.sources[def.base()] = ValueSource{SourceCommandLine, arg}
+		} else if err != nil {
 			break argLoop
+		} else {
+			p.counts[def.base()]++
+			if def.base().deprecated != "" {
+				p.recordDeprecatedUse(def.base())
+			}
+			p.sources[def.base()] = ValueSource{SourceCommandLine, arg}
 		}
 		if moreShorts == "" {
 			// go on to next argument unless we had extra shorts concatenated with this option
 			i++
 		}
 	}
-	// copy output list to Args
-	Args = append([]string{}, argsOut...)
-	return argsOut, err
+	// use the default command if no command was given
+	if err == nil && mode.only == nil && p.command == nil && self.defaultCommand() != nil {
+		var rest []string
+		if rest, err = p.parseCommand(self.defaultCommand(), []string{}, mode); err != nil {
+			return p, rest, err
+		}
+		argsOut = append(argsOut, rest...)
+	}
+	if err != nil && err != ErrHelp && err != ErrVersion {
+		// a problem with the argument at i stopped the parse; report it with
+		// any collected ones
+		err = &ParseError{Index: mode.offset + i, Token: args[i], Err: err}
+		mode.report(self, err)
+	}
+	if (err == ErrHelp || err == ErrVersion) && len(errs) > 0 {
+		// the problems collected before the help or version was shown are
+		// still errors
+		err = errs.err()
+		errs = nil // they are all in err now
+		mode.report(self, err)
+	} else if err != nil && len(errs) > 0 {
+		err = append(errs, err)
+	}
+	if err == nil {
+		// fill in options that weren't given from any other sources, then
+		// check that all positional arguments and required options were
+		// given, and the relationships between the options that were given
+		stop := self.collect(&errs, p.loadConfigFiles())
+		stop = stop || self.collect(&errs, p.applySources(mode.only))
+		stop = stop || self.collect(&errs, p.applyRemembered(remembered, mode.only))
+		checked := argsOut // the arguments for the validators, without the terminator
+		if terminator >= 0 {
+			checked = append(append([]string{}, argsOut[:terminator]...), argsOut[terminator+1:]...)
+		}
+		switch {
+		case stop || mode.only != nil:
+		case len(self.commands) > 0 && p.command == nil:
+			self.collect(&errs, fmt.Errorf("Missing command"))
+		case self.collect(&errs, p.assignPositionals(posArgs)):
+		case self.collect(&errs, self.checkArgs(checked)):
+		case self.collect(&errs, p.checkRequired()):
+		case self.collect(&errs, p.checkMinCounts()):
+		case self.collect(&errs, p.checkConstraints()):
+		case len(errs) == 0:
+			self.collect(&errs, self.runFinally())
+		}
+		if err = errs.err(); err != nil {
+			errs = nil // they are all in err now
+			mode.report(self, err)
+		}
+	}
+	if mode.only != nil {
+		return p, nil, err
+	}
+	if err == nil && self.statePath != "" {
+		p.saveState()
+	}
+	if !mode.detached {
+		// copy output list to Args
+		Args = append([]string{}, argsOut...)
+		UnknownArgs = unknownOut
+	}
+	p.args, p.unknown = argsOut, unknownOut
+	return p, argsOut, err
 }
@@ -1,11 +1,15 @@
 package miniflags
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -105,6 +109,71 @@ func Test_AlternativesOption(t *testing.T) {
 	}
 }
 
+func Test_Choices_Help(t *testing.T) {
+	var color string
+	oset := NewOptionSet().
+		Option("c color", Choices(&color, []string{"red", "green", "blue"}), "=COLOR; Color").
+		Add(Option("h", func() {}, "").Hide())
+	want := []string{"  -c, --color=COLOR Color (one of: red, green, blue)"}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, []string{"red", "green", "blue"}, oset.HelpData().Sections[0].Options[0].Choices, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_IntAlternativesOption(t *testing.T) {
+	var level int
+	tests := []struct {
+		input     []string
+		want      int
+		errPrefix string
+	}{
+		{[]string{"-l", "9"}, 9, ""},
+		{[]string{"--level=0"}, 0, ""},
+		{[]string{"-l", "5"}, -1, "Error with command line option '-l': Invalid parameter value '5'; expected one of: 0, 1, 9"},
+		{[]string{"-l", "x"}, -1, "Error with command line option '-l': Invalid parameter value 'x'; expected one of: 0, 1, 9"},
+	}
+	oset := NewOptionSet().
+		Option("l level", IntAlternativesOption(&level, []int{0, 1, 9}), "=N; Compression level").
+		Add(Option("h", func() {}, "").Hide())
+	for _, test := range tests {
+		level = -1
+		_, err := oset.ParseArgs(test.input)
+		if m := checkValErr(t, test.want, level, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+	want := []string{"  -l, --level=N     Compression level (one of: 0, 1, 9)"}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+type testSetter []string
+
+func (self *testSetter) Set(value string) error {
+	if value == "" {
+		return fmt.Errorf("Empty value")
+	}
+	*self = append(*self, value)
+	return nil
+}
+
+func Test_Setter(t *testing.T) {
+	var values testSetter
+	oset := NewOptionSet(Option("s", &values, ""))
+	_, err := oset.ParseArgs([]string{"-s", "a", "-sb"})
+	if m := checkValErr(t, testSetter{"a", "b"}, values, "", err); m != "" {
+		t.Error(m)
+	}
+	_, err = oset.ParseArgs([]string{"-s", ""})
+	if m := checkValErr(t, testSetter{"a", "b"}, values, "Error with command line option '-s': Empty value", err); m != "" {
+		t.Error(m)
+	}
+}
+
 func Test_OptionDef_takesParameter(t *testing.T) {
 	var b bool
 	var i int
@@ -133,14 +202,14 @@ func Test_isTargetOk(t *testing.T) {
 		input *OptionDef
 		want  bool
 	}{
-		{&OptionDef{"", nil, ""}, true},
-		{&OptionDef{"a", nil, ""}, false},
-		{&OptionDef{"a", 3, ""}, false},
-		{&OptionDef{"a", &i, ""}, true},
-		{&OptionDef{"a", &s, ""}, true},
-		{&OptionDef{"a", &a, ""}, true},
-		{&OptionDef{"a", func() {}, ""}, true},
-		{&OptionDef{"a", func(int) {}, ""}, false},
+		{Option("", nil, ""), true},
+		{Option("a", nil, ""), false},
+		{Option("a", 3, ""), false},
+		{Option("a", &i, ""), true},
+		{Option("a", &s, ""), true},
+		{Option("a", &a, ""), true},
+		{Option("a", func() {}, ""), true},
+		{Option("a", func(int) {}, ""), false},
 	}
 	for _, test := range tests {
 		got := test.input.isTargetOk()
@@ -195,6 +264,24 @@ func Test_OptionSet_ArgAction(t *testing.T) {
 	}
 }
 
+func Test_OptionSet_ArgsHelp(t *testing.T) {
+	var src string
+	oset := NewOptionSet().
+		Positional("SRC", &src, "The source").
+		ArgAction(func(string) {}).
+		ArgsHelp("FILE...", "Input files to process")
+	want := []string{
+		"  SRC               The source",
+		"  FILE...           Input files to process",
+	}
+	if m := checkValErr(t, want, oset.FormatArgumentsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, 2, len(oset.HelpData().Arguments), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
 func Test_OptionSet_Add(t *testing.T) {
 	e1 := Option("x", func() {}, "")
 	e2 := Option(" y yyy ", func() {}, "")
@@ -248,6 +335,41 @@ func Test_OptionSet_Add(t *testing.T) {
 	}
 }
 
+func Test_OptionSet_AddE(t *testing.T) {
+	var n int
+	set := NewOptionSet()
+	if err := set.AddE(Option("n", &n, "")); err != nil {
+		t.Error(err)
+	}
+	err := set.OptionE("x", "BAD TARGET", "")
+	if m := checkValErr(t, nil, nil, "Unsupported target type for option '-x'", err); m != "" {
+		t.Error(m)
+	}
+	// the entries of a call with a mistake are not added
+	var a bool
+	err = set.AddE(Option("a", &a, ""), Option("n", &n, ""))
+	if m := checkValErr(t, nil, nil, "Option name 'n' defined more than once", err); m != "" {
+		t.Error(m)
+	}
+	_, err = set.ParseArgs([]string{"-n", "3"})
+	if m := checkValErr(t, 3, n, "", err); m != "" {
+		t.Error(m)
+	}
+	_, err = set.ParseArgs([]string{"-a"})
+	if m := checkValErr(t, nil, nil, "Unknown option '-a'", err); m != "" {
+		t.Error(m)
+	}
+
+	defer func() {
+		err, _ := recover().(error)
+		if m := checkValErr(t, nil, nil, "Option name 'n' defined more than once", err); m != "" {
+			t.Error(m)
+		}
+	}()
+	NewOptionSet().MustAdd(Option("n", &n, "")).MustAdd(Option("n", &n, ""))
+	t.Error("MustAdd did not panic")
+}
+
 func Test_formatOptionsHelp(t *testing.T) {
 	defer func() { AutoHelp = true }()
 	AutoHelp = false
@@ -326,7 +448,7 @@ func Test_OptionDef_set(t *testing.T) {
 		f = 0
 		b = false
 		a = nil
-		err := test.def.set(test.arg)
+		err := test.def.set(test.arg, nil)
 		if m := checkValErr(t, true, test.checker(), test.errPrefix, err); m != "" {
 			t.Error(m)
 		}
@@ -382,3 +504,865 @@ func Test_OptionSet_ParseArgs(t *testing.T) {
 		}
 	}
 }
+
+func Test_OptionSet_AllowUnknown(t *testing.T) {
+	var b bool
+	var s string
+	var tests = []struct {
+		input       []string
+		wantArgs    []string
+		wantUnknown []string
+		wantS       string
+	}{
+		{[]string{"a", "--foo=1", "-b"}, []string{"a"}, []string{"--foo=1"}, ""},
+		{[]string{"--foo", "1", "-s", "x"}, []string{"1"}, []string{"--foo"}, "x"},
+		{[]string{"-bxyz", "-sx"}, []string{}, []string{"-xyz"}, "x"},
+		{[]string{"-x=3", "y"}, []string{"y"}, []string{"-x=3"}, ""},
+	}
+	for _, test := range tests {
+		s = ""
+		args, err := NewOptionSet().
+			Option("b", &b, "").
+			Option("s", &s, "").
+			AllowUnknown().
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.wantArgs, args, "", err); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantUnknown, UnknownArgs, "", nil); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantS, s, "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionSet_Translate(t *testing.T) {
+	defer func() { AutoHelp = true }()
+	AutoHelp = false
+	var ws string
+	var v bool
+	base := NewOptionSet().
+		Option("w workspace", &ws, "=DIR; Workspace directory").
+		Section("Other:").
+		Option("v verbose", &v, "Verbose output")
+	branded := base.Translate(map[string]string{"workspace": "project", "w": "p", "v": ""})
+
+	args, err := branded.ParseArgs([]string{"-p", "foo", "--verbose", "x"})
+	if m := checkValErr(t, []string{"x"}, args, "", err); m != "" {
+		t.Error(m)
+	}
+	if ws != "foo" || !v {
+		t.Errorf("Got targets '%s', %v; expected 'foo', true", ws, v)
+	}
+	_, err = branded.ParseArgs([]string{"--workspace", "bar"})
+	if m := checkValErr(t, nil, nil, "Unknown option '--workspace'", err); m != "" {
+		t.Error(m)
+	}
+
+	want := []string{
+		"  -p, --project=DIR Workspace directory",
+		"Other:",
+		"  --verbose         Verbose output",
+	}
+	if m := checkValErr(t, want, branded.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	// original set is unchanged
+	if base.Lookup("workspace") == nil || base.Lookup("project") != nil {
+		t.Error("Translate modified the original option set")
+	}
+
+	_, err = base.Translate(map[string]string{"v": "w"}).ParseArgs([]string{})
+	if m := checkValErr(t, nil, nil, "Option name 'w' defined more than once", err); m != "" {
+		t.Error(m)
+	}
+
+	// constraints, loaded config values and config file options follow the
+	// translation, and leave the original set alone
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tool.ini")
+	if err := os.WriteFile(path, []byte("name = ann\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var name string
+	base = NewOptionSet().
+		Option("w workspace", &ws, "").
+		Option("v verbose", &v, "").
+		Option("name", &name, "").
+		RequireTogether("workspace", "verbose")
+	base.Option("config", ConfigFileOption(base), "")
+	if err := base.ReadINI(strings.NewReader("name = bob\n"), "tool.ini"); err != nil {
+		t.Fatal(err)
+	}
+	branded = base.Translate(map[string]string{"workspace": "project", "w": ""})
+	v = false
+	_, err = branded.ParseArgs([]string{"--project=x"})
+	if m := checkValErr(t, "bob", name, "Option '--verbose' must be given together with '--project'", err); m != "" {
+		t.Error(m)
+	}
+	_, err = branded.ParseArgs([]string{"--config", path})
+	if m := checkValErr(t, "ann", name, "", err); m != "" {
+		t.Error(m)
+	}
+	base.Reset()
+	_, err = base.ParseArgs([]string{})
+	if m := checkValErr(t, "bob", name, "", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_UnknownAction(t *testing.T) {
+	var seen []string
+	var tests = []struct {
+		input     interface{}
+		want      []string
+		errPrefix string
+	}{
+		{func(opt string) { seen = append(seen, opt) }, []string{"--foo=1", "-yz"}, ""},
+		{func(opt string) error {
+			if opt == "-yz" {
+				return fmt.Errorf("No plugin")
+			}
+			seen = append(seen, opt)
+			return nil
+		}, []string{"--foo=1"}, "Error with command line option '-yz': No plugin"},
+		{"BAD TARGET", nil, "Unsupported target type for unknown option action"},
+	}
+	for _, test := range tests {
+		seen = nil
+		args, err := NewOptionSet().
+			UnknownAction(test.input).
+			Option("x", func() {}, "").
+			ParseArgs([]string{"a", "--foo=1", "-xyz"})
+		if m := checkValErr(t, test.want, seen, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+		if test.errPrefix == "" {
+			if m := checkValErr(t, []string{"a"}, args, "", nil); m != "" {
+				t.Error(m)
+			}
+		}
+	}
+}
+
+func Test_OptionSet_Strict(t *testing.T) {
+	var n int
+	var b bool
+	var tests = []struct {
+		input     []*OptionDef
+		errPrefix string
+	}{
+		{[]*OptionDef{Section("Main:"), Option("n", &n, "=N; Number"), Option("b", &b, "Flag")}, ""},
+		{[]*OptionDef{Section("Main:"), Option("n", &n, "=N; "), Option("b", &b, "Flag")}, "Option '-n' has no help text"},
+		{[]*OptionDef{Section("Main:"), Option("n", &n, "Number")}, "Option '-n' has no parameter name"},
+		{[]*OptionDef{Option("b", &b, "Flag"), Section("Main:")}, "Option '-b' is not in a section"},
+	}
+	for _, test := range tests {
+		_, err := NewOptionSet(test.input...).Strict().ParseArgs([]string{})
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionSet_ArgContext(t *testing.T) {
+	type input struct {
+		name    string
+		quality int
+	}
+	var inputs []*input
+	var verbose bool
+	quality := 5
+	oset := NewOptionSet().
+		Option("v verbose", &verbose, "").
+		Option("q quality", &quality, "").
+		ArgContext(func(arg string) *OptionSet {
+			in := &input{arg, quality}
+			inputs = append(inputs, in)
+			return NewOptionSet().Option("q quality", &in.quality, "")
+		})
+
+	args, err := oset.ParseArgs([]string{"-q", "7", "a.png", "-v", "b.png", "--quality=9", "c.png"})
+	if m := checkValErr(t, []string{"a.png", "b.png", "c.png"}, args, "", err); m != "" {
+		t.Error(m)
+	}
+	want := []*input{{"a.png", 7}, {"b.png", 9}, {"c.png", 7}}
+	if m := checkValErr(t, want, inputs, "", nil); m != "" {
+		t.Error(m)
+	}
+	if !verbose {
+		t.Error("Global option in context was not set")
+	}
+
+	_, err = NewOptionSet().
+		ArgContext(func(arg string) *OptionSet {
+			return NewOptionSet().Option("x", "BAD TARGET", "")
+		}).
+		ParseArgs([]string{"a"})
+	if m := checkValErr(t, nil, nil, "Unsupported target type for option '-x'", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Terminators(t *testing.T) {
+	var b bool
+	var tests = []struct {
+		terminators []string
+		input       []string
+		want        []string
+		wantB       bool
+		errPrefix   string
+	}{
+		{nil, []string{"a", "--", "-b", "--"}, []string{"a", "--", "-b", "--"}, false, ""},
+		{nil, []string{"a", ";", "-b"}, []string{"a", ";"}, true, ""},
+		{[]string{";"}, []string{"-b", ";", "-x"}, []string{";", "-x"}, true, ""},
+		{[]string{";"}, []string{"--", "-b"}, []string{}, false, "Unknown option '--'"},
+		{[]string{"--", ";"}, []string{"a", ";", "-b"}, []string{"a", ";", "-b"}, false, ""},
+	}
+	for _, test := range tests {
+		b = false
+		oset := NewOptionSet().Option("b", &b, "")
+		if test.terminators != nil {
+			oset.Terminators(test.terminators...)
+		}
+		args, err := oset.ParseArgs(test.input)
+		if m := checkValErr(t, test.want, args, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantB, b, "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionSet_Negations(t *testing.T) {
+	defer func() { AutoHelp = true }()
+	AutoHelp = false
+	var color, x, noX bool
+	oset := NewOptionSet().
+		Option("c color", &color, "Colorize output").
+		Option("x", &x, "").
+		Option("no-x", func() { noX = true }, "").
+		Negations()
+	var tests = []struct {
+		input     []string
+		wantColor bool
+		wantNoX   bool
+		errPrefix string
+	}{
+		{[]string{}, true, false, ""},
+		{[]string{"--no-color"}, false, false, ""},
+		{[]string{"--no-color", "--color"}, true, false, ""},
+		{[]string{"--no-x"}, true, true, ""},
+		{[]string{"--no-c"}, true, false, "Unknown option '--no-c'"},
+	}
+	for _, test := range tests {
+		color, noX = true, false
+		_, err := oset.ParseArgs(test.input)
+		if m := checkValErr(t, test.wantColor, color, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantNoX, noX, "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+	_, err := oset.ParseArgs([]string{"--no-color"})
+	if m := checkValErr(t, ValueSource{SourceCommandLine, "--no-color"}, oset.Source("color"), "", err); m != "" {
+		t.Error(m)
+	}
+	want := "  -c, --[no-]color  Colorize output"
+	if m := checkValErr(t, want, oset.FormatOptionsHelp()[0], "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Values(t *testing.T) {
+	var (
+		n    = 3
+		s    string
+		b    bool
+		list []string
+	)
+	oset := NewOptionSet().
+		Option("n number", &n, "").
+		Option("  s  ", &s, "").
+		Section("Section").
+		Option("b flag bool", &b, "").
+		Option("list", &list, "").
+		Option("x", func() {}, "")
+	_, err := oset.ParseArgs([]string{"-n", "4", "-s", "foo", "--bool", "--list=a"})
+	values := oset.Values()
+	want := map[string]interface{}{"number": 4, "s": "foo", "flag": true, "list": []string{"a"}}
+	if m := checkValErr(t, want, values, "", err); m != "" {
+		t.Error(m)
+	}
+	list[0] = "changed"
+	if m := checkValErr(t, []string{"a"}, values["list"], "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_explicitBool(t *testing.T) {
+	var f, g bool
+	var tests = []struct {
+		input     []string
+		wantF     bool
+		wantG     bool
+		errPrefix string
+	}{
+		{[]string{"--flag"}, true, false, ""},
+		{[]string{"--flag=false"}, false, false, ""},
+		{[]string{"--flag=YES", "-g=1"}, true, true, ""},
+		{[]string{"-f=off", "-g=On"}, false, true, ""},
+		{[]string{"-fg"}, true, true, ""},
+		{[]string{"-gf=0"}, false, true, ""},
+		{[]string{"--flag=maybe"}, true, false, "Error with command line option '--flag=maybe': Invalid boolean value 'maybe'"},
+	}
+	for _, test := range tests {
+		f, g = true, false
+		_, err := NewOptionSet().
+			Option("f flag", &f, "").
+			Option("g", &g, "").
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.wantF, f, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantG, g, "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionDef_MaxCount(t *testing.T) {
+	var out string
+	var v bool
+	var tests = []struct {
+		input     []string
+		errPrefix string
+	}{
+		{[]string{"-o", "a"}, ""},
+		{[]string{"-o", "a", "--output=b"}, "Option '--output' given more than once"},
+		{[]string{"-vv", "--verbose"}, ""},
+		{[]string{"-vv", "--verbose", "--no-verbose"}, "Option '--verbose' given more than 3 times"},
+	}
+	for _, test := range tests {
+		_, err := NewOptionSet().
+			Add(Option("o output", &out, "").MaxCount(1)).
+			Add(Option("v verbose", &v, "").MaxCount(3)).
+			Negations().
+			ParseArgs(test.input)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_parameterEquals(t *testing.T) {
+	var d string
+	var tests = []struct {
+		raw   bool
+		input []string
+		want  string
+	}{
+		{false, []string{"--define=key=value"}, "key=value"},
+		{false, []string{"--define==x"}, "=x"},
+		{false, []string{"--define", "=x"}, "=x"},
+		{false, []string{"-Dkey=value"}, "key=value"},
+		{false, []string{"-D=key=value"}, "key=value"},
+		{false, []string{"-D==x"}, "=x"},
+		{false, []string{"-D", "=x"}, "=x"},
+		{true, []string{"-D=x"}, "=x"},
+		{true, []string{"-Dkey=value"}, "key=value"},
+		{true, []string{"--define=key=value"}, "key=value"},
+	}
+	for _, test := range tests {
+		d = ""
+		oset := NewOptionSet().Option("D define", &d, "")
+		if test.raw {
+			oset.RawShortParameters()
+		}
+		_, err := oset.ParseArgs(test.input)
+		if m := checkValErr(t, test.want, d, "", err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionSet_ParseKnownArgs(t *testing.T) {
+	var n int
+	var b bool
+	var tests = []struct {
+		input     []string
+		want      []string
+		wantN     int
+		wantB     bool
+		errPrefix string
+	}{
+		{[]string{"a", "--lib-opt", "x", "-n", "3", "b"}, []string{"a", "--lib-opt", "x", "b"}, 3, false, ""},
+		{[]string{"-bz=1", "--lib=2", "-y"}, []string{"-z=1", "--lib=2", "-y"}, 0, true, ""},
+		{[]string{"-n4", "--", "-b", "c"}, []string{"--", "-b", "c"}, 4, false, ""},
+		{[]string{"-x", "-n"}, []string{"-x"}, 0, false, "Expected a parameter after option '-n'"},
+	}
+	for _, test := range tests {
+		n, b = 0, false
+		args, err := NewOptionSet().
+			Option("n", &n, "").
+			Option("b", &b, "").
+			ParseKnownArgs(test.input)
+		if m := checkValErr(t, test.want, args, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if m := checkValErr(t, test.wantN, n, "", nil); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantB, b, "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionSet_ParseOnly(t *testing.T) {
+	defer fakeEnv(map[string]string{"CONFIG": "env.conf"})()
+	var config, out string
+	var verbose, b bool
+	var tests = []struct {
+		input       []string
+		wantConfig  string
+		wantVerbose bool
+		errPrefix   string
+	}{
+		{[]string{}, "env.conf", false, ""},
+		{[]string{"-o", "--config", "a", "--config=x.conf", "-bv"}, "x.conf", true, ""},
+		{[]string{"--unknown", "-x", "a", "--verbose=false", "-o"}, "env.conf", false, ""},
+		{[]string{"--out=z", "--config"}, "", false, "Expected a parameter after option '--config'"},
+	}
+	for _, test := range tests {
+		config, out, verbose, b = "", "", false, false
+		oset := NewOptionSet().
+			Add(Option("config", &config, "").Env("CONFIG")).
+			Option("o out", &out, "").
+			Option("v verbose", &verbose, "").
+			Add(Option("b", &b, "").MaxCount(1))
+		Args = nil
+		err := oset.ParseOnly(append([]string{"-b", "-b"}, test.input...), "config", "verbose")
+		if m := checkValErr(t, test.wantConfig, config, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if m := checkValErr(t, test.wantVerbose, verbose, "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+		if out != "" || b || Args != nil {
+			t.Errorf("Skipped options were processed: %q, %v, %q", out, b, Args)
+		}
+	}
+
+	err := NewOptionSet().ParseOnly([]string{}, "config")
+	if m := checkValErr(t, nil, nil, "Unknown option name 'config' given to ParseOnly", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Reset(t *testing.T) {
+	var (
+		n    = 3
+		s    = "def"
+		b    bool
+		list = []string{"x"}
+		none []string
+		src  string
+	)
+	oset := NewOptionSet().
+		Option("n", &n, "").
+		Option("s", &s, "").
+		Option("b", &b, "").
+		Option("l", &list, "").
+		Option("z", &none, "").
+		Option("f", func() {}, "").
+		Positional("SRC", &src, "")
+	_, err := oset.ParseArgs([]string{"-n4", "-s", "x", "-b", "-l", "y", "-z", "z", "a"})
+	if m := checkValErr(t, []string{"x", "y"}, list, "", err); m != "" {
+		t.Error(m)
+	}
+	oset.Reset()
+	got := []interface{}{n, s, b, list, none, src, oset.Source("n").Kind}
+	want := []interface{}{3, "def", false, []string{"x"}, []string(nil), "", SourceDefault}
+	if m := checkValErr(t, want, got, "", nil); m != "" {
+		t.Error(m)
+	}
+	// the saved default is not shared with the target
+	_, err = oset.ParseArgs([]string{"-l", "w", "a"})
+	if m := checkValErr(t, []string{"x", "w"}, list, "", err); m != "" {
+		t.Error(m)
+	}
+	oset.Reset()
+	if m := checkValErr(t, []string{"x"}, list, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_ParseArgsWith(t *testing.T) {
+	var name string
+	var n int
+	set := NewOptionSet().
+		Option("name", &name, "").
+		Option("n", &n, "").
+		ErrorHandling(ContinueOnError)
+	var wg sync.WaitGroup
+	failures := make(chan string, 100)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			args := []string{"-n", strconv.Itoa(i), "arg"}
+			if i%2 == 0 {
+				args = append(args, "--name", strconv.Itoa(i))
+			}
+			err := set.ParseArgsWith(args, func(result *ParseResult, err error) error {
+				want := []interface{}{[]string{"arg"}, nil, i, ""}
+				if i%2 == 0 {
+					want[3] = strconv.Itoa(i)
+				}
+				if m := checkValErr(t, want, []interface{}{result.Args, err, n, name}, "", nil); m != "" {
+					failures <- m
+				}
+				return fmt.Errorf("done %d", i)
+			})
+			if err == nil || err.Error() != fmt.Sprintf("done %d", i) {
+				failures <- fmt.Sprintf("got error %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(failures)
+	for m := range failures {
+		t.Error(m)
+	}
+}
+
+func Test_OptionDef_Hide(t *testing.T) {
+	defer func() { AutoHelp = true }()
+	AutoHelp = false
+	var color string
+	var debug bool
+	oset := NewOptionSet().
+		Add(Option("c color colour", &color, "=WHEN; Colorize output").Hide("colour")).
+		Add(Option("debug-internal", &debug, "Internal debugging").Hide())
+	_, err := oset.ParseArgs([]string{"--colour=never", "--debug-internal"})
+	if m := checkValErr(t, "never", color, "", err); m != "" {
+		t.Error(m)
+	}
+	if !debug {
+		t.Error("Hidden option was not parsed")
+	}
+	want := []string{"  -c, --color=WHEN  Colorize output"}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionDef_Implicit(t *testing.T) {
+	defer func() { AutoHelp = true }()
+	AutoHelp = false
+	var color string
+	var level int
+	var tests = []struct {
+		input     []string
+		wantColor string
+		wantLevel int
+		wantArgs  []string
+		errPrefix string
+	}{
+		{[]string{}, "", 0, []string{}, ""},
+		{[]string{"--color", "never"}, "auto", 0, []string{"never"}, ""},
+		{[]string{"--color=never"}, "never", 0, []string{}, ""},
+		{[]string{"-c", "-l"}, "auto", 1, []string{}, ""},
+		{[]string{"-cnever", "-l3"}, "never", 3, []string{}, ""},
+		{[]string{"-c=always", "--level=2"}, "always", 2, []string{}, ""},
+		{[]string{"--level="}, "", 0, []string{}, "Error with command line option '--level=': strconv.ParseInt"},
+	}
+	for _, test := range tests {
+		color, level = "", 0
+		args, err := NewOptionSet().
+			Add(Option("c color", &color, "=WHEN; Colorize output").Implicit("auto")).
+			Add(Option("l level", &level, "").Implicit("1")).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.wantArgs, args, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if m := checkValErr(t, []interface{}{test.wantColor, test.wantLevel}, []interface{}{color, level}, "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+	oset := NewOptionSet().
+		Add(Option("c color", &color, "=WHEN; Colorize output").Implicit("auto")).
+		Add(Option("l level", &level, "Set level").Implicit("1"))
+	want := []string{"  -c, --color[=WHEN]", "                    Colorize output", "  -l, --level       Set level"}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	options := oset.HelpData().Sections[0].Options
+	if m := checkValErr(t, []string{"[=WHEN]", ""}, []string{options[0].ValueName, options[1].ValueName}, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_HelpColumn(t *testing.T) {
+	var num int
+	var verbose bool
+	var tests = []struct {
+		indent, column int
+		want           []string
+	}{
+		{2, 0, []string{
+			"  -n, --number-of-items=NUM",
+			"                    Number of items",
+			"  -v, --verbose     Verbose output",
+		}},
+		{2, 12, []string{
+			"  -n, --number-of-items=NUM",
+			"            Number of items",
+			"  -v, --verbose",
+			"            Verbose output",
+		}},
+		{2, -1, []string{
+			"  -n, --number-of-items=NUM  Number of items",
+			"  -v, --verbose              Verbose output",
+		}},
+		{4, -1, []string{
+			"    -n, --number-of-items=NUM  Number of items",
+			"    -v, --verbose              Verbose output",
+		}},
+	}
+	for _, test := range tests {
+		oset := NewOptionSet().
+			Option("n number-of-items", &num, "=NUM; Number of items").
+			Option("v verbose", &verbose, "Verbose output").
+			Add(Option("h", func() {}, "").Hide()).
+			HelpIndent(test.indent).
+			HelpColumn(test.column)
+		if m := checkValErr(t, test.want, oset.FormatOptionsHelp(), "", nil); m != "" {
+			t.Error(test.indent, test.column, m)
+		}
+	}
+}
+
+func Test_OptionSet_SortHelp(t *testing.T) {
+	var flag bool
+	oset := NewOptionSet().
+		Option("z zebra", &flag, "Z").
+		Option("a", &flag, "A").
+		Option("b apple", &flag, "B").
+		Section("Other:").
+		Option("y yak", &flag, "Y").
+		Option("c cat", &flag, "C").
+		SortHelp()
+	want := []string{
+		"  -a                A",
+		"  -b, --apple       B",
+		"  -z, --zebra       Z",
+		"Other:",
+		"  -c, --cat         C",
+		"  -h, --help        Print this help message and exit",
+		"  -y, --yak         Y",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_ShowDefaults(t *testing.T) {
+	num, ratio, name, flag, list := 3, 0.5, "joe", false, []string{"a", "b"}
+	var empty string
+	oset := NewOptionSet().
+		Option("n number", &num, "=NUM; Number value").
+		Option("ratio", &ratio, "=R; Ratio").
+		Option("name", &name, "=NAME; Name").
+		Option("empty", &empty, "=S; Empty").
+		Option("f flag", &flag, "Flag").
+		Option("l list", &list, "=ITEM; List").
+		Option("E", func() {}, "Function").
+		Add(Option("h", func() {}, "").Hide()).
+		ShowDefaults()
+	num = 4 // the value when the option was added is shown
+	want := []string{
+		"  -n, --number=NUM  Number value (default: 3)",
+		"  --ratio=R         Ratio (default: 0.5)",
+		"  --name=NAME       Name (default: joe)",
+		"  --empty=S         Empty",
+		"  -f, --flag        Flag",
+		"  -l, --list=ITEM   List (default: a, b)",
+		"  -E                Function",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_FormatOptionsHelp_Paragraphs(t *testing.T) {
+	var mode string
+	oset := NewOptionSet().
+		Option("m mode", &mode, "=MODE; Select the mode.\n    Modes are fast and slow.\n\nThe default is fast.").
+		Add(Option("h", func() {}, "").Hide())
+	want := []string{
+		"  -m, --mode=MODE   Select the mode.",
+		"                    Modes are fast and slow.",
+		"",
+		"                    The default is fast.",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionDef_Advanced(t *testing.T) {
+	var flag bool
+	newSet := func() *OptionSet {
+		return NewOptionSet().
+			Option("v verbose", &flag, "Verbose").
+			Add(Option("trace", &flag, "Trace").Advanced()).
+			Section("Tuning:").
+			Add(Option("threads", &flag, "Threads").Advanced()).
+			Section("Output:").
+			Option("q quiet", &flag, "Quiet")
+	}
+	want := []string{
+		"  -v, --verbose     Verbose",
+		"Output:",
+		"  -q, --quiet       Quiet",
+		"  -h, --help        Print this help message and exit",
+	}
+	if m := checkValErr(t, want, newSet().FormatBriefOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+
+	savedUsage, savedBriefUsage := Usage, BriefUsage
+	defer func() { Usage, BriefUsage = savedUsage, savedBriefUsage }()
+	shown := ""
+	Usage = func(*OptionSet) { shown = "full" }
+	BriefUsage = func(*OptionSet) { shown = "brief" }
+	var tests = []struct {
+		oset  *OptionSet
+		input string
+		want  string
+	}{
+		{newSet(), "-h", "brief"},
+		{newSet(), "--help", "full"},
+		{NewOptionSet(Option("v", &flag, "")), "-h", "full"},
+	}
+	for _, test := range tests {
+		shown = ""
+		err := test.oset.Execute(context.Background(), []string{test.input})
+		if m := checkValErr(t, test.want, shown, "Help requested", err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionSet_WrapWidth(t *testing.T) {
+	var mode string
+	oset := NewOptionSet().
+		Option("m mode", &mode, "=MODE; Select the processing mode used for every input file.\n\nSee the manual.").
+		Option("x", func() {}, "Short").
+		Add(Option("h", func() {}, "").Hide()).
+		WrapWidth(41)
+	want := []string{
+		"  -m, --mode=MODE   Select the processing",
+		"                    mode used for every",
+		"                    input file.",
+		"",
+		"                    See the manual.",
+		"  -x                Short",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_wrapText(t *testing.T) {
+	var tests = []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"a b c", 0, []string{"a b c"}},
+		{"aa bb cc", 5, []string{"aa bb", "cc"}},
+		{"aaaaaaa bb", 5, []string{"aaaaaaa", "bb"}},
+		{"", 5, []string{""}},
+		{"日本 語", 5, []string{"日本", "語"}},
+	}
+	for _, test := range tests {
+		if m := checkValErr(t, test.want, wrapText(test.text, test.width), "", nil); m != "" {
+			t.Error(test.text, m)
+		}
+	}
+}
+
+func Test_OptionDef_Metavar(t *testing.T) {
+	var out, in string
+	var num int
+	oset := NewOptionSet().
+		Section("Files:").
+		Add(Option("o output", &out, "Output file").Metavar("FILE")).
+		Add(Option("i input", &in, "=NAME; Input file").Metavar("PATH")).
+		Add(Option("n", &num, "=NUM; Count")).
+		Add(Option("h", func() {}, "Help").Hide()).
+		Strict()
+	want := []string{
+		"Files:",
+		"  -o, --output=FILE Output file",
+		"  -i, --input=PATH  Input file",
+		"  -n=NUM            Count",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	_, err := oset.ParseArgs([]string{"-o", "x"})
+	if m := checkValErr(t, "x", out, "", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_AlignSections(t *testing.T) {
+	var flag bool
+	var src string
+	oset := NewOptionSet().
+		Option("a", &flag, "A").
+		Option("b", &flag, "B").
+		Section("Long:").
+		Option("a-very-long-option-name", &flag, "Long").
+		Add(Option("h", func() {}, "").Hide()).
+		Positional("SRC", &src, "Source").
+		AlignSections()
+	want := []string{
+		"  -a  A",
+		"  -b  B",
+		"Long:",
+		"  --a-very-long-option-name  Long",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, []string{"  SRC  Source"}, oset.FormatArgumentsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionDef_Tag(t *testing.T) {
+	var flag bool
+	def := Option("turbo", &flag, "Go faster").Tag("experimental").Tag("since v2.1")
+	oset := NewOptionSet(def, Option("h", func() {}, "").Hide())
+	want := []string{"  --turbo           Go faster [experimental] [since v2.1]"}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	tags := []string{"experimental", "since v2.1"}
+	if m := checkValErr(t, []interface{}{tags, tags}, []interface{}{def.Tags(), oset.HelpData().Sections[0].Options[0].Tags}, "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,259 @@
+/*
+Package miniflagstest runs script-style regression tests against a
+miniflags.OptionSet. A script is a text archive in the txtar format: a
+sequence of files, each introduced by a marker line of the form
+"-- name --". Each file in the archive is run as a separate subtest, using a
+fresh OptionSet returned by the client's setup function. Any text before the
+first marker is a comment and is ignored.
+
+Each line of a file is a directive. Blank lines and lines starting with '#'
+are ignored. The supported directives are:
+
+	args ARG...      Parse the given command line. Arguments are separated by
+	                 spaces; single or double quotes may be used to group
+	                 words containing spaces into one argument.
+	value NAME WANT  Check that the target of the option NAME holds a value
+	                 that formats (with fmt.Sprint) as WANT.
+	rest ARG...      Check the non-option arguments returned by the last parse.
+	error PREFIX     Check that the last parse failed with an error message
+	                 starting with PREFIX. If a parse fails and there is no
+	                 error directive after it, the test fails.
+	help             The remaining lines of the file are the expected output
+	                 of FormatOptionsHelp.
+
+Example script:
+
+	Tests for the number option.
+	-- long form --
+	args --number 8 a
+	value number 8
+	rest a
+	-- bad value --
+	args --number=x
+	error Error with command line option '--number=x'
+	-- help --
+	help
+	  -n, --number=NUM  Number value
+
+While a script runs, miniflags.OnError is replaced with a no-op so that
+parse errors don't exit the test program.
+*/
+package miniflagstest
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jsthayer/miniflags"
+)
+
+// File is one named file in a script archive.
+type File struct {
+	Name  string   // The name given in the file's marker line
+	Lines []string // The lines of the file, without line terminators
+}
+
+// ParseArchive splits the txtar-formatted text into its files. Any comment
+// text before the first file marker is discarded.
+func ParseArchive(text string) []File {
+	files := []File{}
+	var cur *File
+	for _, line := range strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n") {
+		if name, ok := fileMarker(line); ok {
+			files = append(files, File{Name: name})
+			cur = &files[len(files)-1]
+		} else if cur != nil {
+			cur.Lines = append(cur.Lines, line)
+		}
+	}
+	// drop the empty line produced by a trailing newline
+	if cur != nil && len(cur.Lines) > 0 && cur.Lines[len(cur.Lines)-1] == "" {
+		cur.Lines = cur.Lines[:len(cur.Lines)-1]
+	}
+	return files
+}
+
+// Check if line is a txtar file marker; if so return the file name.
+func fileMarker(line string) (string, bool) {
+	if !strings.HasPrefix(line, "-- ") || !strings.HasSuffix(line, " --") || len(line) < 7 {
+		return "", false
+	}
+	return strings.TrimSpace(line[3 : len(line)-3]), true
+}
+
+// Run runs each file in the txtar-formatted script as a subtest of t. The
+// newSet function is called once per file and must return a freshly built
+// OptionSet whose targets hold their default values.
+func Run(t *testing.T, newSet func() *miniflags.OptionSet, script string) {
+	saved := miniflags.OnError
+	miniflags.OnError = func(*miniflags.OptionSet, ...interface{}) {}
+	defer func() { miniflags.OnError = saved }()
+
+	for _, file := range ParseArchive(script) {
+		file := file
+		t.Run(file.Name, func(t *testing.T) {
+			for _, msg := range RunFile(newSet(), file) {
+				t.Error(msg)
+			}
+		})
+	}
+}
+
+// RunPath reads a script from the named file and runs it as with Run.
+func RunPath(t *testing.T, newSet func() *miniflags.OptionSet, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Run(t, newSet, string(data))
+}
+
+// RunFile performs the directives in one script file against set, and
+// returns a list of failure messages. The list is empty if all checks pass.
+// Unlike Run, RunFile does not replace miniflags.OnError.
+func RunFile(set *miniflags.OptionSet, file File) []string {
+	failures := []string{}
+	fail := func(lineNo int, format string, a ...interface{}) {
+		failures = append(failures, fmt.Sprintf("line %d: ", lineNo)+fmt.Sprintf(format, a...))
+	}
+
+	var rest []string  // non-option args from the last parse
+	var err error      // error from the last parse
+	errChecked := true // the error from the last parse was checked by a directive
+	parsed := false    // a parse has been performed
+	parseLine := 0     // line number of the last parse
+
+	for i := 0; i < len(file.Lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(file.Lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		verb, operand := line, ""
+		if split := strings.IndexByte(line, ' '); split >= 0 {
+			verb, operand = line[:split], strings.TrimSpace(line[split+1:])
+		}
+
+		switch verb {
+		case "args":
+			if err != nil && !errChecked {
+				fail(parseLine, "Got unexpected error '%v'", err)
+			}
+			words, splitErr := SplitWords(operand)
+			if splitErr != nil {
+				fail(lineNo, "%v", splitErr)
+				continue
+			}
+			rest, err = set.ParseArgs(words)
+			errChecked = err == nil
+			parsed = true
+			parseLine = lineNo
+
+		case "value":
+			split := strings.IndexByte(operand, ' ')
+			name, want := operand, ""
+			if split >= 0 {
+				name, want = operand[:split], strings.TrimSpace(operand[split+1:])
+			}
+			def := set.Lookup(name)
+			if def == nil {
+				fail(lineNo, "No option named '%s'", name)
+				continue
+			}
+			got, ok := targetValue(def.Target())
+			if !ok {
+				fail(lineNo, "Target of option '%s' is not a variable", name)
+			} else if got != want {
+				fail(lineNo, "Option '%s': got '%s', expected '%s'", name, got, want)
+			}
+
+		case "rest":
+			want, splitErr := SplitWords(operand)
+			if splitErr != nil {
+				fail(lineNo, "%v", splitErr)
+			} else if !parsed {
+				fail(lineNo, "No args directive before rest")
+			} else if !reflect.DeepEqual(want, rest) {
+				fail(lineNo, "Got arguments %q, expected %q", rest, want)
+			}
+
+		case "error":
+			switch {
+			case !parsed:
+				fail(lineNo, "No args directive before error")
+			case err == nil:
+				fail(lineNo, "Expected error starting with '%s', got no error", operand)
+			case !strings.HasPrefix(err.Error(), operand):
+				fail(lineNo, "Expected error starting with '%s', got error '%v'", operand, err)
+			}
+			errChecked = true
+
+		case "help":
+			want := strings.Join(file.Lines[i+1:], "\n")
+			got := strings.Join(set.FormatOptionsHelp(), "\n")
+			if got != want {
+				fail(lineNo, "Got help:\n%s\nexpected:\n%s", got, want)
+			}
+			i = len(file.Lines)
+
+		default:
+			fail(lineNo, "Unknown directive '%s'", verb)
+		}
+	}
+	if err != nil && !errChecked {
+		fail(parseLine, "Got unexpected error '%v'", err)
+	}
+	return failures
+}
+
+// Return the value referenced by a pointer target formatted with fmt.Sprint.
+// Returns false if the target is not a pointer, e.g. a setter function.
+func targetValue(target interface{}) (string, bool) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return "", false
+	}
+	return fmt.Sprint(v.Elem().Interface()), true
+}
+
+// SplitWords splits a directive operand into words separated by spaces.
+// Single or double quotes group characters, including spaces, into a word;
+// the quotes themselves are removed. An empty result is an empty, non-nil
+// list.
+func SplitWords(text string) ([]string, error) {
+	words := []string{}
+	var word []byte
+	inWord := false // a word has been started, possibly an empty quoted one
+	var quote byte  // the quote character currently open, if any
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+			word = append(word, c)
+		case c == '\'' || c == '"':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				words = append(words, string(word))
+				word = word[:0]
+				inWord = false
+			}
+		default:
+			word = append(word, c)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("Unterminated quote in '%s'", text)
+	}
+	if inWord {
+		words = append(words, string(word))
+	}
+	return words, nil
+}
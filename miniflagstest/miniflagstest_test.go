@@ -0,0 +1,80 @@
+package miniflagstest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jsthayer/miniflags"
+)
+
+func newTestSet() *miniflags.OptionSet {
+	var (
+		num  = 3
+		flag bool
+		list []string
+	)
+	return miniflags.NewOptionSet().
+		Option("n number", &num, "=NUM; Number value").
+		Option("f flag", &flag, "Boolean flag").
+		Option("list", &list, "=ITEM; String list value")
+}
+
+func Test_RunPath(t *testing.T) {
+	RunPath(t, newTestSet, "testdata/example.txtar")
+}
+
+func Test_ParseArchive(t *testing.T) {
+	files := ParseArchive("comment\n-- a --\nargs -x\n\n-- b c --\nhelp\n")
+	want := []File{
+		{"a", []string{"args -x", ""}},
+		{"b c", []string{"help"}},
+	}
+	if !reflect.DeepEqual(want, files) {
+		t.Errorf("Got '%v', expected '%v'", files, want)
+	}
+}
+
+func Test_RunFile_failures(t *testing.T) {
+	saved := miniflags.OnError
+	miniflags.OnError = func(*miniflags.OptionSet, ...interface{}) {}
+	defer func() { miniflags.OnError = saved }()
+
+	var tests = []struct {
+		lines []string
+		want  string // prefix of the single expected failure
+	}{
+		{[]string{"args -n 4", "value n 5"}, "line 2: Option 'n': got '4', expected '5'"},
+		{[]string{"args -n x"}, "line 1: Got unexpected error"},
+		{[]string{"args -n 4", "error Error"}, "line 2: Expected error starting with 'Error', got no error"},
+		{[]string{"args a", "rest b"}, `line 2: Got arguments ["a"], expected ["b"]`},
+		{[]string{"value zzz 1"}, "line 1: No option named 'zzz'"},
+		{[]string{"frobnicate"}, "line 1: Unknown directive 'frobnicate'"},
+		{[]string{"args 'a"}, "line 1: Unterminated quote"},
+		{[]string{"help", "nope"}, "line 1: Got help:"},
+	}
+	for _, test := range tests {
+		got := RunFile(newTestSet(), File{"test", test.lines})
+		if len(got) != 1 || !strings.HasPrefix(got[0], test.want) {
+			t.Errorf("Got %q, expected one failure starting with '%s'", got, test.want)
+		}
+	}
+}
+
+func Test_SplitWords(t *testing.T) {
+	var tests = []struct {
+		input string
+		want  []string
+	}{
+		{"", []string{}},
+		{"a  b", []string{"a", "b"}},
+		{`'a b' "c'd" ''`, []string{"a b", "c'd", ""}},
+		{"--x='1 2'", []string{"--x=1 2"}},
+	}
+	for _, test := range tests {
+		got, err := SplitWords(test.input)
+		if err != nil || !reflect.DeepEqual(test.want, got) {
+			t.Errorf("Got %q (%v), expected %q", got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package miniflags
+
+import "os"
+
+// Normalize attaches functions that rewrite the raw parameter of this option
+// before it is converted, validated and stored, such as strings.TrimSpace,
+// strings.ToLower or ExpandEnv. They are called in order on every value,
+// whether it comes from the command line, the environment or a config file,
+// so that for example an AlternativesOption target accepts "Red" as "red".
+// Returns self so that calls can be chained.
+func (self *OptionDef) Normalize(funcs ...func(string) string) *OptionDef {
+	self.normalizers = append(self.normalizers, funcs...)
+	return self
+}
+
+// ExpandEnv replaces ${var} or $var in value with the value of the
+// environment variable, or with "" if it is not set, for use with
+// Normalize. Variables are looked up with LookupEnv.
+func ExpandEnv(value string) string {
+	return os.Expand(value, func(key string) string {
+		value, _ := LookupEnv(key)
+		return value
+	})
+}
+
+// Apply the normalizers of this option to a raw parameter.
+func (self *OptionDef) normalize(value string) string {
+	for _, normalizer := range self.normalizers {
+		value = normalizer(value)
+	}
+	return value
+}
@@ -0,0 +1,38 @@
+package miniflags
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_OptionDef_Normalize(t *testing.T) {
+	var name, colorValue, path string
+	var level int
+	var tests = []struct {
+		input     []string
+		env       map[string]string
+		want      []interface{}
+		errPrefix string
+	}{
+		{[]string{"--name", "  Bob ", "--color=Red", "--level= 3"}, nil, []interface{}{"Bob", "red", 3, ""}, ""},
+		{[]string{"--path=$HOME/x"}, map[string]string{"HOME": "/home/me"}, []interface{}{"", "", 0, "/home/me/x"}, ""},
+		{[]string{"--path=${UNSET}x"}, nil, []interface{}{"", "", 0, "x"}, ""},
+		{[]string{}, map[string]string{"COLOR": " BLUE"}, []interface{}{"", "blue", 0, ""}, ""},
+		{[]string{"--color=Pink"}, nil, []interface{}{"", "", 0, ""}, "Error with command line option '--color=Pink': Invalid parameter value 'pink'"},
+	}
+	for _, test := range tests {
+		name, colorValue, path, level = "", "", "", 0
+		color := AlternativesOption(&colorValue, []string{"red", "blue"})
+		restore := fakeEnv(test.env)
+		_, err := NewOptionSet().
+			Add(Option("name", &name, "").Normalize(strings.TrimSpace)).
+			Add(Option("color", color, "").Env("COLOR").Normalize(strings.TrimSpace, strings.ToLower)).
+			Add(Option("level", &level, "").Normalize(strings.TrimSpace).Range(0, 9)).
+			Add(Option("path", &path, "").Normalize(ExpandEnv)).
+			ParseArgs(test.input)
+		restore()
+		if m := checkValErr(t, test.want, []interface{}{name, colorValue, level, path}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
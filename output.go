@@ -0,0 +1,134 @@
+package miniflags
+
+// EmitFunc sets the function that writes the user-visible message lines of
+// this set and its commands, such as the usage message and warnings,
+// overriding the package-level Emit variable. This lets two independently
+// configured parsers write to different places in one process. Returns self
+// so that calls can be chained.
+func (self *OptionSet) EmitFunc(emit func(a ...interface{})) *OptionSet {
+	self.emit = emit
+	return self
+}
+
+// UsageFunc sets the function that displays the usage message of this set
+// and its commands, overriding the package-level Usage variable. Returns self
+// so that calls can be chained.
+func (self *OptionSet) UsageFunc(usage func(defs *OptionSet)) *OptionSet {
+	self.usage = usage
+	return self
+}
+
+// OnErrorFunc sets the function called when parsing this set or its
+// commands encounters an error, overriding the package-level OnError
+// variable. It is only called if the ErrorHandling of the set is
+// ExitOnError. Returns self so that calls can be chained.
+func (self *OptionSet) OnErrorFunc(onError func(defs *OptionSet, a ...interface{})) *OptionSet {
+	self.onError = onError
+	return self
+}
+
+// WarningFunc sets a function that receives the non-fatal warnings of this
+// set and its commands, such as the use of a deprecated option, a repeated
+// value dropped by Unique, or an unknown config key allowed by
+// WarnUnknownConfig. The message has no "Warning: " prefix. Without a
+// WarningFunc, an unknown config key or a failure to save remembered values
+// is written with the Emit method as a "Warning: " line, while the other
+// warnings are only written if EmitWarnings is used. Warnings don't stop the
+// parse, and those issued during a parse are also listed in its ParseResult.
+// Returns self so that calls can be chained.
+func (self *OptionSet) WarningFunc(warning func(message string)) *OptionSet {
+	self.warning = warning
+	return self
+}
+
+// Emit writes a user-visible message line for this set, with the function
+// set with EmitFunc on it or on the nearest set it is a command of, or else
+// with the Emit variable. Custom Usage functions can use it to write their
+// output.
+func (self *OptionSet) Emit(a ...interface{}) {
+	for set := self; set != nil; set = set.parent {
+		if set.emit != nil {
+			set.emit(a...)
+			return
+		}
+	}
+	Emit(a...)
+}
+
+// Display the usage message of this set, with the function set with
+// UsageFunc on it or on the nearest set it is a command of, or else with the
+// Usage variable.
+func (self *OptionSet) showUsage() {
+	for set := self; set != nil; set = set.parent {
+		if set.usage != nil {
+			set.usage(self)
+			return
+		}
+	}
+	Usage(self)
+}
+
+// Pass an error to the function set with OnErrorFunc on this set or on the
+// nearest set it is a command of, or else to the OnError variable.
+func (self *OptionSet) onErrorFunc(a ...interface{}) {
+	for set := self; set != nil; set = set.parent {
+		if set.onError != nil {
+			set.onError(self, a...)
+			return
+		}
+	}
+	OnError(self, a...)
+}
+
+// EmitWarnings makes the warnings of this set and its commands that are
+// otherwise only passed to a WarningFunc and listed in the ParseResult, such
+// as the use of a deprecated option or a repeated value dropped by Unique,
+// also be written with the Emit method as "Warning: " lines when there is no
+// WarningFunc. Returns self so that calls can be chained.
+func (self *OptionSet) EmitWarnings() *OptionSet {
+	self.emitWarnings = true
+	return self
+}
+
+// Report a warning, passing it to the function set with WarningFunc on this
+// set or on the nearest set it is a command of, or else to the Emit method
+// with a "Warning: " prefix.
+func (self *OptionSet) warn(message string) {
+	self.report(message, true)
+}
+
+// Report a warning as with warn, except that without a WarningFunc it is
+// only written with the Emit method if EmitWarnings was used on this set or a
+// set it is a command of.
+func (self *OptionSet) warnQuietly(message string) {
+	self.report(message, false)
+}
+
+// Pass the warning message to the nearest WarningFunc, or else to the Emit
+// method if emit is true or EmitWarnings was used.
+func (self *OptionSet) report(message string, emit bool) {
+	for set := self; set != nil; set = set.parent {
+		if set.warning != nil {
+			set.warning(message)
+			return
+		}
+		emit = emit || set.emitWarnings
+	}
+	if emit {
+		self.Emit("Warning: " + message)
+	}
+}
+
+// Report a warning issued during the parse as with the warn method of the
+// set, keeping it for the ParseResult.
+func (self *parser) warn(message string) {
+	self.warnings = append(self.warnings, message)
+	self.set.warn(message)
+}
+
+// Report a warning issued during the parse as with the warnQuietly method of
+// the set, keeping it for the ParseResult.
+func (self *parser) warnQuietly(message string) {
+	self.warnings = append(self.warnings, message)
+	self.set.warnQuietly(message)
+}
@@ -0,0 +1,110 @@
+package miniflags
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_EmitFunc(t *testing.T) {
+	savedOnError, savedEmit := OnError, Emit
+	defer func() { OnError, Emit = savedOnError, savedEmit }()
+	global := []string{}
+	Emit = func(a ...interface{}) { global = append(global, fmt.Sprint(a...)) }
+	OnError = func(*OptionSet, ...interface{}) { global = append(global, "global error") }
+
+	var n int
+	newSet := func(out *[]string, errs *[]string) *OptionSet {
+		return NewOptionSet().Option("n", &n, "").
+			AddCommand(Command("sub", NewOptionSet(), func([]string) error { return nil }, "")).
+			EmitFunc(func(a ...interface{}) { *out = append(*out, fmt.Sprint(a...)) }).
+			OnErrorFunc(func(defs *OptionSet, a ...interface{}) { *errs = append(*errs, fmt.Sprint(a...)) })
+	}
+	out1, errs1, out2, errs2 := []string{}, []string{}, []string{}, []string{}
+	set1, set2 := newSet(&out1, &errs1), newSet(&out2, &errs2)
+	set1.ErrorHandling(ContinueOnError).ParseArgs([]string{"--help"})
+	set2.ParseArgs([]string{"sub", "--bogus"})
+
+	if len(out1) == 0 || !strings.HasPrefix(out1[0], "Usage:") || len(errs1) != 0 {
+		t.Errorf("first set: got output %q and errors %q", out1, errs1)
+	}
+	if len(out2) != 0 || len(errs2) != 1 || errs2[0] != "Unknown option '--bogus'" {
+		t.Errorf("second set: got output %q and errors %q", out2, errs2)
+	}
+	if len(global) != 0 {
+		t.Errorf("got global output %q", global)
+	}
+
+	usages := []string{}
+	set := NewOptionSet().
+		AddCommand(Command("sub", NewOptionSet(), func([]string) error { return nil }, "")).
+		UsageFunc(func(defs *OptionSet) { usages = append(usages, defs.commandName()) }).
+		ErrorHandling(ContinueOnError)
+	set.ParseArgs([]string{"sub", "-h"})
+	set.ParseArgs([]string{"--help"})
+	if m := checkValErr(t, []string{"sub", ""}, usages, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_WarningFunc(t *testing.T) {
+	savedEmit := Emit
+	defer func() { Emit = savedEmit }()
+	emitted := []string{}
+	Emit = func(a ...interface{}) { emitted = append(emitted, fmt.Sprint(a...)) }
+
+	var tags []string
+	var out string
+	newSet := func() *OptionSet {
+		return NewOptionSet().
+			Add(Option("tag", &tags, "").Unique(true)).
+			Add(Option("out", &out, "").Deprecated("use --output")).
+			AddCommand(Command("sub", NewOptionSet(), func([]string) error { return nil }, ""))
+	}
+	input := []string{"--tag=a", "--out=x", "--tag=a", "sub"}
+	want := []string{"Option '--out' is deprecated: use --output", "Repeated value 'a' for --tag ignored"}
+
+	tags = nil
+	warnings := []string{}
+	result, err := newSet().WarningFunc(func(message string) { warnings = append(warnings, message) }).Parse(input)
+	if m := checkValErr(t, []interface{}{want, want, []string{}, []string{"a"}}, []interface{}{warnings, result.Warnings, emitted, tags}, "", err); m != "" {
+		t.Error(m)
+	}
+
+	// without a WarningFunc, these warnings are only written if asked for
+	tags = nil
+	if _, err := newSet().ParseArgs(input); err != nil {
+		t.Error(err)
+	}
+	if m := checkValErr(t, []string{}, emitted, "", nil); m != "" {
+		t.Error(m)
+	}
+	tags = nil
+	if _, err := newSet().EmitWarnings().ParseArgs(input); err != nil {
+		t.Error(err)
+	}
+	if m := checkValErr(t, []string{"Warning: " + want[0], "Warning: " + want[1]}, emitted, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_WarningFunc_Dropped(t *testing.T) {
+	var tags []string
+	set := NewOptionSet().
+		Add(Option("tag", &tags, "").Unique(true)).
+		AddCommand(Command("sub", NewOptionSet(), func([]string) error { return nil }, "")).
+		ErrorHandling(ContinueOnError)
+
+	// the parse stops at the unknown command, but still reports the value
+	result, err := set.Parse([]string{"--tag=a", "--tag=a", "bogus"})
+	if m := checkValErr(t, []string{"Repeated value 'a' for --tag ignored"}, result.Warnings, "Unknown command", err); m != "" {
+		t.Error(m)
+	}
+
+	// a later parse doesn't report it again
+	tags = nil
+	result, err = set.Parse([]string{"--tag=b", "sub"})
+	if m := checkValErr(t, []string(nil), result.Warnings, "", err); m != "" {
+		t.Error(m)
+	}
+}
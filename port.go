@@ -0,0 +1,61 @@
+package miniflags
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// PortOption is a factory function that can be called to create an Option
+// target value for a TCP or UDP port. The parameter may be a port number from
+// 1 to 65535 or a service name such as "https", which is looked up with
+// net.LookupPort for the network, "tcp" or "udp". The port number is stored
+// in target. For example:
+//
+//	Option("p port", PortOption(&port, "tcp"), "=PORT; Port to listen on")
+func PortOption(target *int, network string) func(val string) error {
+	return func(val string) error {
+		port, err := parsePort(network, val)
+		if err != nil {
+			return err
+		}
+		*target = port
+		return nil
+	}
+}
+
+// Port returns a Validator requiring a port number from 1 to 65535. For a
+// string value, such as that of a *string target, a TCP service name such as
+// "https" that net.LookupPort knows is also accepted.
+func Port() Validator {
+	return func(name string, value interface{}) error {
+		var valid bool
+		if s, ok := value.(string); ok {
+			_, err := parsePort("tcp", s)
+			valid = err == nil
+		} else if f, ok := toFloat(value); ok {
+			valid = f >= 1 && f <= 65535 && f == float64(int(f))
+		} else {
+			return nil
+		}
+		if !valid {
+			return fmt.Errorf("value %v for %s must be a port number from 1 to 65535 or a service name", value, name)
+		}
+		return nil
+	}
+}
+
+// Convert a port number or service name for the network to a port number.
+func parsePort(network, value string) (int, error) {
+	if n, err := strconv.Atoi(value); err == nil {
+		if n < 1 || n > 65535 {
+			return 0, fmt.Errorf("port %d is out of range 1-65535", n)
+		}
+		return n, nil
+	}
+	port, err := net.LookupPort(network, value)
+	if err != nil || port == 0 {
+		return 0, fmt.Errorf("unknown port '%s'", value)
+	}
+	return port, nil
+}
@@ -0,0 +1,50 @@
+package miniflags
+
+import (
+	"testing"
+)
+
+func Test_PortOption(t *testing.T) {
+	var port int
+	var tests = []struct {
+		input     []string
+		want      int
+		errPrefix string
+	}{
+		{[]string{"--port=8080"}, 8080, ""},
+		{[]string{"--port=65535"}, 65535, ""},
+		{[]string{"--port=0"}, -1, "Error with command line option '--port=0': port 0 is out of range 1-65535"},
+		{[]string{"--port=70000"}, -1, "Error with command line option '--port=70000': port 70000 is out of range 1-65535"},
+		{[]string{"--port=no-such-service"}, -1, "Error with command line option '--port=no-such-service': unknown port 'no-such-service'"},
+	}
+	for _, test := range tests {
+		port = -1
+		_, err := NewOptionSet(Option("port", PortOption(&port, "tcp"), "")).ParseArgs(test.input)
+		if m := checkValErr(t, test.want, port, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_Port(t *testing.T) {
+	var tests = []struct {
+		value     interface{}
+		errPrefix string
+	}{
+		{443, ""},
+		{uint64(1), ""},
+		{0, "value 0 for --port must be a port number from 1 to 65535 or a service name"},
+		{65536, "value 65536 for --port must be a port number"},
+		{1.5, "value 1.5 for --port must be a port number"},
+		{"8080", ""},
+		{"99999", "value 99999 for --port must be a port number"},
+		{"no-such-service", "value no-such-service for --port must be a port number"},
+		{true, ""},
+	}
+	for _, test := range tests {
+		err := Port()("--port", test.value)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.value, m)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package miniflags
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Positional returns a new OptionDef that defines a named positional
+// argument, such as SRC or DEST, rather than an option. When added to an
+// OptionSet, positional arguments receive the non-option arguments in the
+// order they were defined, and the arguments they receive are not included in
+// the list returned by ParseArgs. The target may be any type accepted by the
+// Option call that takes a parameter. By default a positional argument must be
+// given exactly once, except that one with a *[]string target takes one or
+// more arguments; use Arity to change this. The names of positional arguments
+// appear in the usage header, and any help text is shown in an "Arguments:"
+// block of the usage message.
+func Positional(name string, target interface{}, help string) *OptionDef {
+	def := &OptionDef{names: name, target: target, help: help, positional: true, minArgs: 1, maxArgs: 1}
+	if def.isList() {
+		def.maxArgs = -1
+	}
+	return def
+}
+
+// Positional is equivalent to calling Add(Positional(name, target, help)) on
+// this OptionSet. Returns self so that calls can be chained.
+func (self *OptionSet) Positional(name string, target interface{}, help string) *OptionSet {
+	return self.Add(Positional(name, target, help))
+}
+
+// Arity sets the minimum and maximum number of arguments that a positional
+// argument takes. A max less than zero means there is no limit. Only a
+// positional argument with a *[]string target may take more than one
+// argument; with a min of zero, the argument is optional. When the arguments
+// are assigned, each positional argument takes as many as it can, in order,
+// while leaving enough for the minimums of the ones after it, so definitions
+// such as "SRC... DEST" work as expected. If too few arguments are given,
+// ParseArgs reports an error such as "Expected at least 1 FILE argument".
+// Returns self so that calls can be chained.
+func (self *OptionDef) Arity(min, max int) *OptionDef {
+	self.minArgs, self.maxArgs = min, max
+	return self
+}
+
+// Check a positional argument definition and add it to the set.
+func (self *OptionSet) addPositional(def *OptionDef) {
+	if self.setupError == nil {
+		switch {
+		case def.setupError != nil:
+			self.setupError = def.setupError
+		case def.names == "" || strings.ContainsAny(def.names, " "):
+			self.setupError = fmt.Errorf("Invalid positional argument name '%s'", def.names)
+		case !def.isTargetOk() || def.target == nil || !def.takesParameter():
+			self.setupError = fmt.Errorf("Unsupported target type for argument %s", def.names)
+		case def.minArgs < 0 || (def.maxArgs >= 0 && def.maxArgs < def.minArgs):
+			self.setupError = fmt.Errorf("Invalid arity for argument %s", def.names)
+		case !def.isList() && (def.maxArgs < 0 || def.maxArgs > 1):
+			self.setupError = fmt.Errorf("Argument %s must have a list target to take more than one value", def.names)
+		}
+	}
+	self.positionals = append(self.positionals, def)
+}
+
+// Return the total number of arguments that the positional argument
+// definitions can take, or -1 if there is no limit.
+func (self *OptionSet) positionalCapacity() int {
+	total := 0
+	for _, def := range self.positionals {
+		if def.maxArgs < 0 {
+			return -1
+		}
+		total += def.maxArgs
+	}
+	return total
+}
+
+// Distribute args among the positional argument definitions of the set and
+// set their targets, recording each use in the counts. Returns an error if
+// there are too few arguments or a conversion fails.
+func (self *parser) assignPositionals(args []string) error {
+	positionals := self.set.positionals
+	// the minimum number of arguments needed by the definitions after each one
+	needed := make([]int, len(positionals)+1)
+	for i := len(positionals) - 1; i >= 0; i-- {
+		needed[i] = needed[i+1] + positionals[i].minArgs
+	}
+	if len(args) < needed[0] {
+		// report the first definition that doesn't get its minimum
+		remaining := len(args)
+		for _, def := range positionals {
+			if remaining < def.minArgs {
+				return def.arityError()
+			}
+			remaining -= def.minArgs
+		}
+	}
+	for i, def := range positionals {
+		n := len(args) - needed[i+1]
+		if def.maxArgs >= 0 && n > def.maxArgs {
+			n = def.maxArgs
+		}
+		if n < def.minArgs {
+			return def.arityError()
+		}
+		for _, arg := range args[:n] {
+			if err := def.set(arg, self); err != nil {
+				return &ErrBadValue{Option: def.names, Value: arg, Source: ValueSource{SourceCommandLine, arg}, Err: err, positional: true}
+			}
+			self.counts[def]++
+			self.sources[def] = ValueSource{SourceCommandLine, arg}
+		}
+		args = args[n:]
+	}
+	return nil
+}
+
+// Return the error for a positional argument given too few times.
+func (self *OptionDef) arityError() error {
+	switch {
+	case self.minArgs == 1 && self.maxArgs == 1:
+		return fmt.Errorf("Missing argument %s", self.names)
+	case self.minArgs == self.maxArgs:
+		return fmt.Errorf("Expected exactly %d %s arguments", self.minArgs, self.names)
+	case self.minArgs == 1:
+		return fmt.Errorf("Expected at least 1 %s argument", self.names)
+	default:
+		return fmt.Errorf("Expected at least %d %s arguments", self.minArgs, self.names)
+	}
+}
+
+// Return the positional argument definitions followed by the entry set with
+// ArgsHelp, if any, in the order they are shown in help output.
+func (self *OptionSet) argumentDefs() []*OptionDef {
+	if self.argsHelp == nil {
+		return self.positionals
+	}
+	return append(self.positionals[:len(self.positionals):len(self.positionals)], self.argsHelp)
+}
+
+// Return the names of the positional arguments as shown in a usage header,
+// e.g. "SRC DEST" or "FILE...".
+func (self *OptionSet) formatPositionalNames() string {
+	names := []string{}
+	for _, def := range self.argumentDefs() {
+		names = append(names, def.positionalName())
+	}
+	return strings.Join(names, " ")
+}
+
+// Return the name of a positional argument as shown in help output, with
+// "..." added if it takes more than one argument, and enclosed in brackets if
+// it is optional.
+func (self *OptionDef) positionalName() string {
+	name := self.names
+	if self.maxArgs < 0 || self.maxArgs > 1 {
+		name += "..."
+	}
+	if self.minArgs == 0 {
+		name = "[" + name + "]"
+	}
+	return name
+}
+
+// UsageHeader sets the first line of the usage message for this set,
+// overriding the package-level UsageHeader variable. This lets each command
+// show its own synopsis, such as "Usage: tool copy [ options ] SRC DEST".
+// Returns self so that calls can be chained.
+func (self *OptionSet) UsageHeader(header string) *OptionSet {
+	self.header = header
+	return self
+}
+
+// Return the header line for the usage message. This is the header set with
+// the UsageHeader method if any, or else the UsageHeader variable. If the
+// variable has not been replaced by the client, a synopsis is generated from
+// the definitions: any command names leading to this set, its required
+// options, "[ options ]" if there are any other options, and the names of its
+// commands or positional arguments.
+func (self *OptionSet) usageHeader() string {
+	switch {
+	case self.header != "":
+		return self.header
+	case UsageHeader != defaultUsageHeader:
+		return UsageHeader
+	}
+	parts := []string{"Usage:", filepath.Base(os.Args[0])}
+	if path := self.commandName(); path != "" {
+		parts = append(parts, path)
+	}
+	if required := self.formatRequiredOptions(); required != "" {
+		parts = append(parts, required)
+	}
+	optional := self.hasOptionalOptions()
+	switch {
+	case len(self.commands) > 0 && optional:
+		parts = append(parts, "[ options ] COMMAND [ arguments ]")
+	case len(self.commands) > 0:
+		parts = append(parts, "COMMAND [ arguments ]")
+	case len(self.argumentDefs()) > 0 && optional:
+		parts = append(parts, "[ options ]", self.formatPositionalNames())
+	case len(self.argumentDefs()) > 0:
+		parts = append(parts, self.formatPositionalNames())
+	case optional:
+		parts = append(parts, "[ options and/or arguments ]")
+	default:
+		parts = append(parts, "[ arguments ]")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Check whether any option shown in help output, including the automatic
+// help option, is not required.
+func (self *OptionSet) hasOptionalOptions() bool {
+	for _, def := range self.helpList(AutoHelp) {
+		if !def.hidden && !def.isSectionHeader() && !def.required {
+			return true
+		}
+	}
+	return false
+}
+
+// FormatArgumentsHelp creates a list of lines of help output for the
+// positional arguments and any arguments described with ArgsHelp, formatted
+// in the same way as FormatOptionsHelp. The list is empty if there are none.
+func (self *OptionSet) FormatArgumentsHelp() []string {
+	out := []string{}
+	padding := self.helpPadding()
+	if self.alignSections {
+		lefts := []string{}
+		for _, def := range self.argumentDefs() {
+			lefts = append(lefts, def.positionalName())
+		}
+		padding = self.fitPadding(lefts)
+	}
+	for _, def := range self.argumentDefs() {
+		out = append(out, self.formatHelpEntry(def.positionalName(), def.fullHelp(def.help, self.showDefaults), padding)...)
+	}
+	return out
+}
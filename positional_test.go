@@ -0,0 +1,154 @@
+package miniflags
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_OptionSet_Positional(t *testing.T) {
+	var src, dest string
+	var count int
+	var files []string
+	var tests = []struct {
+		input     []string
+		wantArgs  []string
+		wantSrc   string
+		wantDest  string
+		wantCount int
+		errPrefix string
+	}{
+		{[]string{"a", "-v", "b", "3", "c"}, []string{"c"}, "a", "b", 3, ""},
+		{[]string{"a", "b", "x"}, []string{}, "a", "b", 0, "Error with argument COUNT 'x': strconv.ParseInt"},
+		{[]string{"a", "b"}, []string{}, "", "", 0, "Missing argument COUNT"},
+		{[]string{"-v"}, []string{}, "", "", 0, "Missing argument SRC"},
+	}
+	for _, test := range tests {
+		src, dest, count = "", "", 0
+		args, err := NewOptionSet().
+			Positional("SRC", &src, "Source file").
+			Add(Positional("DEST", &dest, "")).
+			Positional("COUNT", &count, "").
+			Option("v", func() {}, "").
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.wantArgs, args, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if m := checkValErr(t, []interface{}{test.wantSrc, test.wantDest, test.wantCount},
+			[]interface{}{src, dest, count}, "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	oset := NewOptionSet().
+		Positional("SRC", &src, "").
+		Positional("FILE", &files, "Input files")
+	args, err := oset.ParseArgs([]string{"a", "b", "c"})
+	if m := checkValErr(t, []string{}, args, "", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, []string{"b", "c"}, files, "", nil); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, "[ options ] SRC FILE...", oset.usageHeader()[len(oset.usageHeader())-23:], "", nil); m != "" {
+		t.Error(m)
+	}
+	want := []string{
+		"  SRC               ",
+		"  FILE...           Input files",
+	}
+	if m := checkValErr(t, want, oset.FormatArgumentsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, []string{"b", "c"}, oset.Values()["FILE"], "", nil); m != "" {
+		t.Error(m)
+	}
+
+	var b bool
+	for _, def := range []*OptionDef{Positional("X", &b, ""), Positional("X", func() {}, ""), Positional("A B", &src, "")} {
+		_, err = NewOptionSet(def).ParseArgs([]string{"a"})
+		if err == nil {
+			t.Errorf("Expected setup error for positional '%s'", def.names)
+		}
+	}
+}
+
+func Test_OptionDef_Arity(t *testing.T) {
+	var src, dest []string
+	var opt string
+	var tests = []struct {
+		defs      []*OptionDef
+		input     []string
+		wantSrc   []string
+		wantDest  []string
+		wantOpt   string
+		wantArgs  []string
+		errPrefix string
+	}{
+		// cp-style: SRC... DEST
+		{[]*OptionDef{Positional("SRC", &src, ""), Positional("DEST", &opt, "")},
+			[]string{"a", "b", "c"}, []string{"a", "b"}, nil, "c", []string{}, ""},
+		{[]*OptionDef{Positional("SRC", &src, ""), Positional("DEST", &opt, "")},
+			[]string{"a"}, nil, nil, "", []string{}, "Missing argument DEST"},
+		{[]*OptionDef{Positional("SRC", &src, ""), Positional("DEST", &opt, "")},
+			[]string{}, nil, nil, "", []string{}, "Expected at least 1 SRC argument"},
+		// exactly two, extra arguments are returned
+		{[]*OptionDef{Positional("PAIR", &src, "").Arity(2, 2)},
+			[]string{"a", "b", "c"}, []string{"a", "b"}, nil, "", []string{"c"}, ""},
+		{[]*OptionDef{Positional("PAIR", &src, "").Arity(2, 2)},
+			[]string{"a"}, nil, nil, "", []string{}, "Expected exactly 2 PAIR arguments"},
+		// optional trailing argument
+		{[]*OptionDef{Positional("SRC", &src, "").Arity(1, 2), Positional("OPT", &opt, "").Arity(0, 1)},
+			[]string{"a"}, []string{"a"}, nil, "", []string{}, ""},
+		{[]*OptionDef{Positional("SRC", &src, "").Arity(1, 2), Positional("OPT", &opt, "").Arity(0, 1)},
+			[]string{"a", "b", "c", "d"}, []string{"a", "b"}, nil, "c", []string{"d"}, ""},
+		{[]*OptionDef{Positional("SRC", &src, "").Arity(3, -1), Positional("DEST", &dest, "").Arity(0, -1)},
+			[]string{"a", "b"}, nil, nil, "", []string{}, "Expected at least 3 SRC arguments"},
+		// setup errors
+		{[]*OptionDef{Positional("X", &opt, "").Arity(1, 2)}, []string{}, nil, nil, "", nil, "Argument X must have a list target"},
+		{[]*OptionDef{Positional("X", &src, "").Arity(2, 1)}, []string{}, nil, nil, "", nil, "Invalid arity for argument X"},
+	}
+	for _, test := range tests {
+		src, dest, opt = nil, nil, ""
+		args, err := NewOptionSet(test.defs...).ParseArgs(test.input)
+		if m := checkValErr(t, test.wantArgs, args, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		if m := checkValErr(t, []interface{}{test.wantSrc, test.wantDest, test.wantOpt},
+			[]interface{}{src, dest, opt}, "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	oset := NewOptionSet(Positional("SRC", &src, "").Arity(1, 2), Positional("OPT", &opt, "").Arity(0, 1))
+	if m := checkValErr(t, "SRC... [OPT]", oset.formatPositionalNames(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_usageHeader(t *testing.T) {
+	var name, src string
+	var files []string
+	var force bool
+	noHelp := Option("h", func() {}, "").Hide()
+	var tests = []struct {
+		oset *OptionSet
+		want string
+	}{
+		{NewOptionSet(Option("f force", &force, "")), "[ options and/or arguments ]"},
+		{NewOptionSet(noHelp), "[ arguments ]"},
+		{NewOptionSet(Option("n name", &name, "=NAME;").Required(), noHelp), "--name=NAME [ arguments ]"},
+		{NewOptionSet(Option("n name", &name, "=NAME;").Required(), Option("f force", &force, "")).
+			Positional("SRC", &src, "").Positional("FILE", &files, ""), "--name=NAME [ options ] SRC FILE..."},
+		{NewOptionSet(noHelp).Positional("SRC", &src, ""), "SRC"},
+		{NewOptionSet(noHelp).AddCommand(Command("build", nil, nil, "")), "COMMAND [ arguments ]"},
+		{NewOptionSet(Option("f force", &force, "")).ArgsHelp("FILE...", "Input files"), "[ options ] FILE..."},
+		{NewOptionSet(noHelp).Positional("SRC", &src, "").ArgsHelp("[FILE...]", ""), "SRC [FILE...]"},
+	}
+	prefix := "Usage: " + filepath.Base(os.Args[0]) + " "
+	for _, test := range tests {
+		if m := checkValErr(t, prefix+test.want, test.oset.usageHeader(), "", nil); m != "" {
+			t.Error(m)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package miniflags
+
+import "fmt"
+
+// The default order of precedence of the sources of option values.
+var defaultPrecedence = []Source{SourceCommandLine, SourceEnv, SourceConfig}
+
+// Precedence sets the order in which the sources of option values are
+// considered, from highest to lowest, when more than one supplies a value for
+// an option. The order must list SourceCommandLine, SourceEnv and
+// SourceConfig, each once; the default is that order, so that the command
+// line overrides environment variables, which override config files. The
+// initial value of the target is always the lowest, with any value saved by
+// RememberValues just above it. Each option gets its value from the highest
+// source that has one. If the command line is not the highest, an option
+// given on the command line that is then overridden has its variable target
+// reset to the default before the other value is set, but setter functions
+// will already have been called. An invalid order is reported when ParseArgs
+// is called. Returns self so that calls can be chained.
+func (self *OptionSet) Precedence(sources ...Source) *OptionSet {
+	self.precedence = append([]Source{}, sources...)
+	valid := len(sources) == len(defaultPrecedence)
+	seen := map[Source]bool{}
+	for _, source := range sources {
+		if seen[source] || (source != SourceCommandLine && source != SourceEnv && source != SourceConfig) {
+			valid = false
+		}
+		seen[source] = true
+	}
+	if !valid && self.setupError == nil {
+		self.setupError = fmt.Errorf("Invalid precedence order %v", sources)
+	}
+	return self
+}
+
+// Fill in the values of the options from the sources other than the command
+// line, according to the order of precedence of the set. If only is not nil,
+// only the options in it are considered. Returns any error from converting a
+// value.
+func (self *parser) applySources(only map[*OptionDef]bool) error {
+	order := self.set.precedence
+	if order == nil {
+		order = defaultPrecedence
+	}
+	for _, def := range self.set.list {
+		if only != nil && !only[def] {
+			continue
+		}
+		given := self.counts[def] > 0
+		if !given && def.envDefaulted {
+			self.sources[def] = ValueSource{SourceEnv, def.defaultEnv}
+		}
+		for _, source := range order {
+			var found bool
+			var err error
+			switch source {
+			case SourceCommandLine:
+				found = given
+			case SourceEnv:
+				found, err = self.applyEnv(def, given)
+			case SourceConfig:
+				found, err = self.applyConfig(def, given)
+			}
+			if err != nil {
+				return err
+			}
+			if found {
+				break
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package miniflags
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_Precedence(t *testing.T) {
+	var name string
+	var files []string
+	var tests = []struct {
+		order     []Source
+		env       map[string]string
+		args      []string
+		wantName  string
+		wantFiles []string
+		wantSrc   Source
+		errPrefix string
+	}{
+		{nil, map[string]string{"NAME": "env"}, []string{"--name=cli"}, "cli", []string{"c1", "c2"}, SourceCommandLine, ""},
+		{nil, map[string]string{"NAME": "env"}, []string{}, "env", []string{"c1", "c2"}, SourceEnv, ""},
+		{nil, nil, []string{}, "config", []string{"c1", "c2"}, SourceConfig, ""},
+		{[]Source{SourceConfig, SourceEnv, SourceCommandLine}, map[string]string{"NAME": "env"}, []string{"--name=cli"}, "config", []string{"c1", "c2"}, SourceConfig, ""},
+		{[]Source{SourceEnv, SourceConfig, SourceCommandLine}, map[string]string{"NAME": "env"}, []string{"--name=cli"}, "env", []string{"c1", "c2"}, SourceEnv, ""},
+		{[]Source{SourceEnv, SourceCommandLine, SourceConfig}, nil, []string{"--name=cli", "--file=f"}, "cli", []string{"f"}, SourceCommandLine, ""},
+		{[]Source{SourceConfig, SourceCommandLine, SourceEnv}, nil, []string{"--file=f"}, "config", []string{"c1", "c2"}, SourceConfig, ""},
+		{[]Source{SourceEnv, SourceConfig}, nil, []string{}, "", nil, SourceDefault, "Invalid precedence order [environment config file]"},
+		{[]Source{SourceEnv, SourceEnv, SourceConfig}, nil, []string{}, "", nil, SourceDefault, "Invalid precedence order"},
+		{[]Source{SourcePrompt, SourceEnv, SourceConfig}, nil, []string{}, "", nil, SourceDefault, "Invalid precedence order"},
+	}
+	for _, test := range tests {
+		name, files = "", nil
+		restore := fakeEnv(test.env)
+		oset := NewOptionSet().
+			Add(Option("name", &name, "").Env("NAME")).
+			Option("file", &files, "")
+		if test.order != nil {
+			oset.Precedence(test.order...)
+		}
+		err := oset.ReadINI(strings.NewReader("name = config\nfile = c1\nfile = c2\n"), "test.ini")
+		if err == nil {
+			_, err = oset.ParseArgs(test.args)
+		}
+		restore()
+		if m := checkValErr(t, test.wantName, name, test.errPrefix, err); m != "" {
+			t.Error(test.order, m)
+		}
+		if m := checkValErr(t, test.wantFiles, files, "", nil); m != "" {
+			t.Error(test.order, m)
+		}
+		if m := checkValErr(t, test.wantSrc, oset.Source("name").Kind, "", nil); m != "" {
+			t.Error(test.order, m)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package miniflags
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// StdinIsTerminal is called to check whether Stdin is connected to an
+// interactive terminal before prompting the user. The default checks whether
+// os.Stdin is a character device. This function can be replaced by the client
+// to substitute different behavior.
+var StdinIsTerminal = func() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// PromptOutput is the writer that prompts are written to. The default is
+// os.Stderr.
+var PromptOutput io.Writer = os.Stderr
+
+// PasswordToken is the parameter value that makes a PasswordOption target
+// read the password interactively instead of using the value itself.
+var PasswordToken = "-"
+
+// ReadPassword is called by PasswordOption targets to read a secret. The
+// default writes prompt to PromptOutput and reads a line from Stdin; if
+// StdinIsTerminal returns true, echo is turned off with stty while the line is
+// read. This function can be replaced by the client to substitute different
+// behavior.
+var ReadPassword = func(prompt string) (string, error) {
+	if !StdinIsTerminal() {
+		line, err := readLine(Stdin)
+		if err == io.EOF {
+			err = nil
+		}
+		return line, err
+	}
+	if err := setEcho(false); err != nil {
+		return "", err
+	}
+	defer setEcho(true)
+	fmt.Fprint(PromptOutput, prompt)
+	line, err := readLine(Stdin)
+	fmt.Fprintln(PromptOutput)
+	if err == io.EOF {
+		err = nil
+	}
+	return line, err
+}
+
+// Turn terminal echo for os.Stdin on or off using the stty command.
+func setEcho(on bool) error {
+	arg := "-echo"
+	if on {
+		arg = "echo"
+	}
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("Cannot disable echo: %v", err)
+	}
+	return nil
+}
+
+// PasswordOption is a factory function that can be called to create an Option
+// target value for a secret such as a password. If the parameter is
+// PasswordToken ("-"), the secret is read with ReadPassword, which prompts on
+// the terminal with echo disabled, so the secret doesn't appear in the shell
+// history or the process list. Any other parameter is used as the value. Use
+// Implicit(PasswordToken) to prompt when the option is given with no
+// parameter:
+//
+//	Option("p password", PasswordOption(&password), "Password").Implicit("-")
+func PasswordOption(target *string) func(val string) error {
+	return func(val string) error {
+		if val != PasswordToken {
+			*target = val
+			return nil
+		}
+		secret, err := ReadPassword("Password: ")
+		if err != nil {
+			return err
+		}
+		*target = secret
+		return nil
+	}
+}
+
+// SecretFileOption is a factory function that can be called to create an
+// Option target value for a secret that is read from a file, as in
+// "--password-file PATH", so the secret doesn't appear in the shell history
+// or the process list. The parameter is the name of the file, which is read
+// when the option is parsed; leading and trailing white space, such as a
+// final newline, is removed from the contents. For example:
+//
+//	Option("password-file", SecretFileOption(&password), "=FILE; Read the password from FILE")
+func SecretFileOption(target *string) func(path string) error {
+	return func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		*target = strings.TrimSpace(string(data))
+		return nil
+	}
+}
+
+// PromptMissing enables interactive prompting for missing required options in
+// this set. When a required option that takes a parameter is not given and
+// StdinIsTerminal returns true, the user is prompted for the value with the
+// option's help text, and the line read from Stdin is used as the parameter.
+// If the user enters an empty line, the option is reported as missing as
+// usual. Returns self so that calls can be chained.
+func (self *OptionSet) PromptMissing() *OptionSet {
+	self.promptMissing = true
+	return self
+}
+
+// Prompt the user for the value of def and set it. Returns true if a value
+// was entered, and any error from reading or setting the value.
+func (self *parser) prompt(def *OptionDef) (bool, error) {
+	valName, help := def.splitHelp()
+	text := strings.TrimSpace(help)
+	if text == "" {
+		text = def.displayName() + valName
+	}
+	fmt.Fprintf(PromptOutput, "%s: ", text)
+	line, err := readLine(Stdin)
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("Error reading value for option '%s': %v", def.displayName(), err)
+	}
+	if line == "" {
+		return false, nil
+	}
+	if err = def.setExplicit(line, self); err != nil {
+		return false, fmt.Errorf("Error with value for option '%s': %v", def.displayName(), err)
+	}
+	self.sources[def] = ValueSource{SourcePrompt, def.displayName()}
+	return true, nil
+}
+
+// Read one line from r without reading ahead, so that later reads see the
+// following lines. The line terminator is removed.
+func readLine(r io.Reader) (string, error) {
+	line := []byte{}
+	buf := make([]byte, 1)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				break
+			}
+			line = append(line, buf[0])
+		}
+		if err != nil {
+			return strings.TrimSuffix(string(line), "\r"), err
+		}
+	}
+	return strings.TrimSuffix(string(line), "\r"), nil
+}
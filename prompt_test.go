@@ -0,0 +1,161 @@
+package miniflags
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_OptionDef_Required(t *testing.T) {
+	defer fakeEnv(map[string]string{"NAME": "env"})()
+	var name, other string
+	var tests = []struct {
+		input     []string
+		env       bool
+		errPrefix string
+	}{
+		{[]string{"--name=x"}, false, ""},
+		{[]string{}, true, ""},
+		{[]string{"--other=x"}, false, "Missing required option '--name'"},
+	}
+	for _, test := range tests {
+		nameDef := Option("n name", &name, "").Required()
+		if test.env {
+			nameDef.Env("NAME")
+		}
+		_, err := NewOptionSet(nameDef, Option("other", &other, "")).ParseArgs(test.input)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionSet_PromptMissing(t *testing.T) {
+	savedTerminal := StdinIsTerminal
+	defer func() {
+		Stdin = os.Stdin
+		PromptOutput = os.Stderr
+		StdinIsTerminal = savedTerminal
+	}()
+	var name string
+	var port int
+	var tests = []struct {
+		stdin      string
+		terminal   bool
+		wantName   string
+		wantPort   int
+		wantPrompt string
+		errPrefix  string
+	}{
+		{"joe\r\n8080\n", true, "joe", 8080, "Your name: --port=PORT: ", ""},
+		{"joe\n8080\n", false, "", 0, "", "Missing required option '--name'"},
+		{"\n", true, "", 0, "Your name: ", "Missing required option '--name'"},
+		{"joe\nx\n", true, "joe", 0, "Your name: --port=PORT: ", "Error with value for option '--port': strconv.ParseInt"},
+	}
+	for _, test := range tests {
+		name, port = "", 0
+		out := &bytes.Buffer{}
+		Stdin = strings.NewReader(test.stdin)
+		PromptOutput = out
+		terminal := test.terminal
+		StdinIsTerminal = func() bool { return terminal }
+		oset := NewOptionSet().
+			Add(Option("name", &name, "Your name").Required()).
+			Add(Option("port", &port, "=PORT;").Required()).
+			PromptMissing()
+		_, err := oset.ParseArgs([]string{})
+		if m := checkValErr(t, []interface{}{test.wantName, test.wantPort}, []interface{}{name, port}, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+		if m := checkValErr(t, test.wantPrompt, out.String(), "", nil); m != "" {
+			t.Error(m)
+		}
+		if test.errPrefix == "" {
+			if m := checkValErr(t, ValueSource{SourcePrompt, "--name"}, oset.Source("name"), "", nil); m != "" {
+				t.Error(m)
+			}
+		}
+	}
+}
+
+func Test_PasswordOption(t *testing.T) {
+	savedTerminal := StdinIsTerminal
+	defer func() {
+		Stdin = os.Stdin
+		StdinIsTerminal = savedTerminal
+	}()
+	StdinIsTerminal = func() bool { return false }
+	var password string
+	var tests = []struct {
+		input     []string
+		stdin     string
+		want      string
+		errPrefix string
+	}{
+		{[]string{"--password=secret"}, "", "secret", ""},
+		{[]string{"--password"}, "typed\n", "typed", ""},
+		{[]string{"-p", "-"}, "typed\n", "typed", ""},
+		{[]string{"-p"}, "", "", ""},
+	}
+	for _, test := range tests {
+		password = "unset"
+		Stdin = strings.NewReader(test.stdin)
+		_, err := NewOptionSet(Option("p password", PasswordOption(&password), "Password").Implicit("-")).ParseArgs(test.input)
+		if m := checkValErr(t, test.want, password, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_SecretFileOption(t *testing.T) {
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret")
+	if err := os.WriteFile(path, []byte("  s3cret \n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var password string
+	var tests = []struct {
+		input     []string
+		want      string
+		errPrefix string
+	}{
+		{[]string{}, "unset", ""},
+		{[]string{"--password-file", path}, "s3cret", ""},
+		{[]string{"--password-file=" + filepath.Join(dir, "missing")}, "unset", "Error with command line option '--password-file="},
+	}
+	for _, test := range tests {
+		password = "unset"
+		_, err := NewOptionSet(Option("password-file", SecretFileOption(&password), "=FILE; Password file")).ParseArgs(test.input)
+		if m := checkValErr(t, test.want, password, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OptionDef_Required_Help(t *testing.T) {
+	var name, other string
+	var force bool
+	oset := NewOptionSet().
+		Add(Option("n name", &name, "=NAME; Your name").Required()).
+		Add(Option("f force", &force, "Force it").Required()).
+		Option("other", &other, "=X; Other").
+		Add(Option("h", func() {}, "").Hide())
+	want := []string{
+		"  -n, --name=NAME   Your name (required)",
+		"  -f, --force       Force it (required)",
+		"  --other=X         Other",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	wantHeader := "Usage: " + filepath.Base(os.Args[0]) + " --name=NAME --force [ options and/or arguments ]"
+	if m := checkValErr(t, wantHeader, oset.usageHeader(), "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,42 @@
+package miniflags
+
+import "sync"
+
+// The setup functions registered with Register, in order
+var (
+	registryMutex sync.Mutex
+	registry      []func(set *OptionSet)
+)
+
+// Register saves a setup function that adds options or commands to an
+// OptionSet, for a later call to AddRegistered. This lets packages contribute
+// their own commands and options from their init functions, while the main
+// program assembles the final set:
+//
+//	func init() {
+//		miniflags.Register(func(set *miniflags.OptionSet) {
+//			set.AddCommand(miniflags.Command("serve", serveOptions(), serve, "Run the server"))
+//		})
+//	}
+//
+// The setup function is not called until AddRegistered is, so the commands
+// and options are only built if they are used.
+func Register(setup func(set *OptionSet)) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry = append(registry, setup)
+}
+
+// AddRegistered calls each setup function saved by Register with this set,
+// in the order they were registered. Go runs the init functions of imported
+// packages in a fixed order, so the result is the same on every run. Returns
+// self so that calls can be chained.
+func (self *OptionSet) AddRegistered() *OptionSet {
+	registryMutex.Lock()
+	setups := append([]func(set *OptionSet){}, registry...)
+	registryMutex.Unlock()
+	for _, setup := range setups {
+		setup(self)
+	}
+	return self
+}
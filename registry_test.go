@@ -0,0 +1,27 @@
+package miniflags
+
+import "testing"
+
+func Test_OptionSet_AddRegistered(t *testing.T) {
+	saved := registry
+	defer func() { registry = saved }()
+	registry = nil
+
+	var verbose bool
+	var ran string
+	calls := 0
+	Register(func(set *OptionSet) {
+		calls++
+		set.Add(Option("v verbose", &verbose, ""))
+	})
+	Register(func(set *OptionSet) {
+		set.AddCommand(Command("serve", nil, func([]string) error { ran = "serve"; return nil }, ""))
+	})
+	if calls != 0 {
+		t.Error("Setup function called before AddRegistered")
+	}
+	err := NewOptionSet().AddRegistered().Run([]string{"-v", "serve"})
+	if m := checkValErr(t, []interface{}{true, "serve", 1}, []interface{}{verbose, ran, calls}, "", err); m != "" {
+		t.Error(m)
+	}
+}
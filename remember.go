@@ -0,0 +1,155 @@
+package miniflags
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// RememberValues makes this set remember the option values given on the
+// command line from one run of the program to the next, for interactive
+// tools where users repeat the same options. When ParseArgs is called, the
+// values saved in the state file at path are used as defaults: each is
+// applied to an option that gets no value from the command line, the
+// environment or a config file, with a source of SourceRemembered. After a
+// successful parse, the values of the options given on the command
+// line are added to the file. Only options with a long name and a variable
+// target are remembered; use Ephemeral to leave out others. An error reading
+// the file is reported by ParseArgs, and an error writing it is passed to
+// Emit as a warning. See StatePath for a conventional path. Returns self so
+// that calls can be chained.
+func (self *OptionSet) RememberValues(path string) *OptionSet {
+	self.statePath = path
+	return self
+}
+
+// Ephemeral excludes this option from the values saved by RememberValues.
+// Returns self so that calls can be chained.
+func (self *OptionDef) Ephemeral() *OptionDef {
+	self.ephemeral = true
+	return self
+}
+
+// StatePath returns the conventional path of the state file of the program
+// prog, following the XDG Base Directory specification:
+// "$XDG_STATE_HOME/prog/state.json", where XDG_STATE_HOME is by default
+// ~/.local/state. The variables are read with LookupEnv. Returns "" if the
+// home directory is unknown.
+func StatePath(prog string) string {
+	if dir, ok := LookupEnv("XDG_STATE_HOME"); ok && filepath.IsAbs(dir) {
+		return filepath.Join(dir, prog, "state.json")
+	}
+	if home, ok := LookupEnv("HOME"); ok && home != "" {
+		return filepath.Join(home, ".local", "state", prog, "state.json")
+	}
+	return ""
+}
+
+// Check whether the value of def is saved by RememberValues.
+func (self *OptionDef) isRemembered() bool {
+	if self.ephemeral || self.defaultValue == nil {
+		return false
+	}
+	return len(self.canonicalName()) > 1
+}
+
+// Read the saved values in the state file, if it exists. Returns the values
+// keyed by option name, or an error if the file can't be read.
+func (self *OptionSet) readState() (map[string]interface{}, error) {
+	data, err := os.ReadFile(self.statePath)
+	if os.IsNotExist(err) {
+		return map[string]interface{}{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	state := map[string]interface{}{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&state); err != nil {
+		return nil, fmt.Errorf("Error in state file %s: %v", self.statePath, err)
+	}
+	return state, nil
+}
+
+// Read the values in the state file for the options of this set, to be
+// applied by applyRemembered. Saved values for options that no longer exist
+// or are not remembered are ignored.
+func (self *OptionSet) loadState() (*configBatch, error) {
+	state, err := self.readState()
+	if err != nil {
+		return nil, err
+	}
+	batch := &configBatch{file: self.statePath}
+	for key, saved := range state {
+		def := self.findConfigKey("", key)
+		if def == nil || !def.isRemembered() {
+			continue
+		}
+		values, err := jsonConfigValues(saved)
+		if err != nil {
+			continue
+		}
+		for _, value := range values {
+			batch.add(def, key, self.statePath, value)
+		}
+	}
+	return batch, nil
+}
+
+// Set the options that got no value from any other source during the parse
+// from the values read by loadState. If only is not nil, only the options in
+// it are considered. Returns any error from converting a value.
+func (self *parser) applyRemembered(batch *configBatch, only map[*OptionDef]bool) error {
+	if batch == nil {
+		return nil
+	}
+	for _, def := range batch.order {
+		if (only != nil && !only[def]) || self.sources[def].Kind != SourceDefault {
+			continue
+		}
+		saved := batch.values[def]
+		for _, value := range saved.values {
+			if err := def.setExplicit(value, self); err != nil {
+				return &ErrBadValue{Option: def.displayName(), Value: value,
+					Source: ValueSource{SourceRemembered, saved.key}, Err: err, where: saved.where}
+			}
+		}
+		self.sources[def] = ValueSource{SourceRemembered, saved.key}
+	}
+	return nil
+}
+
+// Add the values of the remembered options given on the command line to the
+// state file of the set. For a list, only the values given are saved, since
+// the list also holds its default values. An error is reported as a warning.
+func (self *parser) saveState() {
+	state, err := self.set.readState()
+	if err == nil {
+		changed := false
+		for _, def := range self.set.list {
+			if self.sources[def].Kind == SourceCommandLine && def.isRemembered() {
+				if def.isList() {
+					state[def.canonicalName()] = self.values[def]
+				} else {
+					state[def.canonicalName()] = reflect.ValueOf(def.target).Elem().Interface()
+				}
+				changed = true
+			}
+		}
+		if !changed {
+			return
+		}
+		var data []byte
+		if data, err = json.MarshalIndent(state, "", "  "); err == nil {
+			if err = os.MkdirAll(filepath.Dir(self.set.statePath), 0700); err == nil {
+				err = os.WriteFile(self.set.statePath, append(data, '\n'), 0600)
+			}
+		}
+	}
+	if err != nil {
+		self.warn(fmt.Sprintf("Cannot save option values: %v", err))
+	}
+}
@@ -0,0 +1,111 @@
+package miniflags
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_OptionSet_RememberValues(t *testing.T) {
+	var name, token string
+	var num int
+	var files []string
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tool", "state.json")
+	var tests = []struct {
+		args      []string
+		wantName  string
+		wantNum   int
+		wantToken string
+		wantFiles []string
+		errPrefix string
+	}{
+		{[]string{}, "", 0, "", nil, ""},
+		{[]string{"--name=bob", "--token=x", "-f", "a", "-f", "b"}, "bob", 0, "x", []string{"a", "b"}, ""},
+		{[]string{}, "bob", 0, "", []string{"a", "b"}, ""},
+		{[]string{"-n", "3", "--name=sue"}, "sue", 3, "", []string{"a", "b"}, ""},
+		{[]string{"-n", "x"}, "", 0, "", nil, "Error with command line option '-n'"},
+		{[]string{}, "sue", 3, "", []string{"a", "b"}, ""},
+	}
+	for _, test := range tests {
+		name, token, num, files = "", "", 0, nil
+		_, err := NewOptionSet().
+			Option("name", &name, "").
+			Option("n number", &num, "").
+			Add(Option("token", &token, "").Ephemeral()).
+			Option("f file", &files, "").
+			RememberValues(path).
+			ParseArgs(test.args)
+		if m := checkValErr(t, test.wantName, name, test.errPrefix, err); m != "" {
+			t.Error(test.args, m)
+		}
+		if m := checkValErr(t, test.wantNum, num, "", nil); m != "" {
+			t.Error(test.args, m)
+		}
+		if m := checkValErr(t, test.wantToken, token, "", nil); m != "" {
+			t.Error(test.args, m)
+		}
+		if m := checkValErr(t, test.wantFiles, files, "", nil); m != "" {
+			t.Error(test.args, m)
+		}
+	}
+
+	// the remembered values are defaults, so a config file overrides them
+	name = ""
+	oset := NewOptionSet().Option("name", &name, "").RememberValues(path)
+	_, err = oset.ParseArgs([]string{})
+	if m := checkValErr(t, []interface{}{"sue", ValueSource{SourceRemembered, "name"}}, []interface{}{name, oset.Source("name")}, "", err); m != "" {
+		t.Error(m)
+	}
+	name = ""
+	oset = NewOptionSet().Option("name", &name, "").RememberValues(path)
+	if err := oset.ReadINI(strings.NewReader("name = ann\n"), "tool.ini"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = oset.ParseArgs([]string{})
+	if m := checkValErr(t, []interface{}{"ann", ValueSource{SourceConfig, "name"}}, []interface{}{name, oset.Source("name")}, "", err); m != "" {
+		t.Error(m)
+	}
+
+	// a list remembers the values given, not its default values
+	listPath := filepath.Join(dir, "list.json")
+	for _, args := range [][]string{{"--tag", "y"}, {}, {}} {
+		tags := []string{"x"}
+		_, err = NewOptionSet().Option("tag", &tags, "").RememberValues(listPath).ParseArgs(args)
+		if m := checkValErr(t, []string{"x", "y"}, tags, "", err); m != "" {
+			t.Error(args, m)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("bad"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewOptionSet().Option("name", &name, "").RememberValues(path).ParseArgs([]string{})
+	if m := checkValErr(t, nil, nil, "Error in state file", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_StatePath(t *testing.T) {
+	var tests = []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{}, ""},
+		{map[string]string{"HOME": "/home/u"}, "/home/u/.local/state/tool/state.json"},
+		{map[string]string{"HOME": "/home/u", "XDG_STATE_HOME": "/state"}, "/state/tool/state.json"},
+	}
+	for _, test := range tests {
+		restore := fakeEnv(test.env)
+		got := StatePath("tool")
+		restore()
+		if m := checkValErr(t, test.want, got, "", nil); m != "" {
+			t.Error(test.env, m)
+		}
+	}
+}
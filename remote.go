@@ -0,0 +1,89 @@
+package miniflags
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// FetchConfig is called by LoadConfigURL to fetch a config file from an
+// "http" or "https" URL. It is passed the URL and any Authorization header
+// value set with ConfigAuthOption, and returns the body of the file and its
+// media type, such as "application/json", or "" if unknown. It is nil by
+// default, so that programs that don't load config files from URLs don't
+// include an HTTP client; importing the package
+// github.com/jsthayer/miniflags/remote sets it. This variable can also be
+// replaced by the client to fetch files in another way.
+var FetchConfig func(url, auth string) (body io.ReadCloser, mediaType string, err error)
+
+// ConfigAuthOption is a factory function that can be called to create an
+// Option target value for the Authorization header sent when set fetches a
+// config file from a URL, such as "Bearer TOKEN". It applies to the config
+// files named by a ConfigFileOption in the same parse, whether it is given
+// before or after them, and is not kept for later parses. For example:
+//
+//	oset.Option("config-auth", ConfigAuthOption(oset), "=VALUE; Authorization for --config URLs")
+func ConfigAuthOption(set *OptionSet) Setter {
+	return &configAuthTarget{set}
+}
+
+// The target value created by ConfigAuthOption.
+type configAuthTarget struct {
+	set *OptionSet // The set whose config files it applies to
+}
+
+// Keep value for the config files loaded into the set outside of a parse,
+// until the set is Reset.
+func (self *configAuthTarget) Set(value string) error {
+	self.set.configAuth = value
+	return nil
+}
+
+// Keep value for the config files of the parse of the set, which is p or a
+// parse that p is a command of. If the set is not being parsed, the value is
+// kept in the set.
+func (self *configAuthTarget) record(value string, p *parser) error {
+	if p = p.forSet(self.set); p == nil {
+		return self.Set(value)
+	}
+	p.configAuth = &value
+	return nil
+}
+
+// Check whether a config file path is a URL to fetch.
+func isConfigURL(path string) bool {
+	lower := strings.ToLower(path)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// LoadConfigURL fetches a config file from an "http" or "https" URL with
+// FetchConfig, and keeps the values in it for the options of this set, as
+// with LoadConfig. Any value set with the Set method of a ConfigAuthOption
+// target is sent as the Authorization header. The file is read with ReadJSON if it has a JSON media
+// type or the URL path ends in ".json", and with ReadINI otherwise. Returns
+// an error if FetchConfig is nil or fails.
+func (self *OptionSet) LoadConfigURL(rawURL string) error {
+	return self.keepConfig(self.loadConfigURL(rawURL, self.configAuth))
+}
+
+// Fetch a config file from a URL as described for LoadConfigURL, sending
+// auth as the Authorization header.
+func (self *OptionSet) loadConfigURL(rawURL, auth string) (*configBatch, error) {
+	if FetchConfig == nil {
+		return nil, fmt.Errorf("Cannot fetch config file %s: loading from URLs is not enabled", rawURL)
+	}
+	body, mediaType, err := FetchConfig(rawURL, auth)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	path := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		path = u.Path
+	}
+	if mediaType == "application/json" || strings.HasSuffix(strings.ToLower(path), ".json") {
+		return self.readJSON(body, rawURL)
+	}
+	return self.readINI(body, rawURL)
+}
@@ -0,0 +1,53 @@
+/*
+Package remote lets miniflags option sets load config files from "http" and
+"https" URLs, as with a "--config https://example.com/tool.ini" option. It is
+kept separate so that programs that don't need it don't include an HTTP
+client. Import it for its side effect of setting miniflags.FetchConfig:
+
+	import _ "github.com/jsthayer/miniflags/remote"
+*/
+package remote
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/jsthayer/miniflags"
+)
+
+// Client is the client used to fetch config files. The default gives up
+// after 10 seconds. This variable can be replaced by the client, for example
+// to change the timeout or add TLS settings.
+var Client = &http.Client{Timeout: 10 * time.Second}
+
+func init() {
+	miniflags.FetchConfig = Fetch
+}
+
+// Fetch gets the config file at url with Client, sending auth as the
+// Authorization header if it is not empty, and returns the response body and
+// its media type. Returns an error if the request fails or the response
+// status is not 200 OK. This is the function installed as
+// miniflags.FetchConfig.
+func Fetch(url, auth string) (io.ReadCloser, string, error) {
+	request, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	if auth != "" {
+		request.Header.Set("Authorization", auth)
+	}
+	response, err := Client.Do(request)
+	if err != nil {
+		return nil, "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, "", fmt.Errorf("Cannot fetch config file %s: %s", url, response.Status)
+	}
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	return response.Body, mediaType, nil
+}
@@ -0,0 +1,63 @@
+package remote
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jsthayer/miniflags"
+)
+
+func Test_Fetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool.ini":
+			fmt.Fprintln(w, "name = ini")
+		case "/tool.json":
+			fmt.Fprintln(w, `{"name": "json"}`)
+		case "/config":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			fmt.Fprintln(w, `{"name": "typed"}`)
+		case "/private":
+			if r.Header.Get("Authorization") != "Bearer xyz" {
+				http.Error(w, "denied", http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintln(w, "name = private")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var name string
+	var tests = []struct {
+		args      []string
+		want      string
+		errPrefix string
+	}{
+		{[]string{"--config", server.URL + "/tool.ini"}, "ini", ""},
+		{[]string{"--config", server.URL + "/tool.json"}, "json", ""},
+		{[]string{"--config", server.URL + "/config"}, "typed", ""},
+		{[]string{"--config-auth=Bearer xyz", "--config", server.URL + "/private"}, "private", ""},
+		{[]string{"--config", server.URL + "/private"}, "", "Error with command line option '--config': Cannot fetch config file " + server.URL + "/private: 401 Unauthorized"},
+		{[]string{"--config", server.URL + "/missing"}, "", "Error with command line option '--config': Cannot fetch config file " + server.URL + "/missing: 404 Not Found"},
+	}
+	for _, test := range tests {
+		name = ""
+		oset := miniflags.NewOptionSet().Option("name", &name, "").ErrorHandling(miniflags.ContinueOnError)
+		oset.Option("config", miniflags.ConfigFileOption(oset), "").
+			Option("config-auth", miniflags.ConfigAuthOption(oset), "")
+		_, err := oset.ParseArgs(test.args)
+		switch {
+		case test.errPrefix == "" && err != nil:
+			t.Errorf("%v: unexpected error %v", test.args, err)
+		case test.errPrefix != "" && (err == nil || !strings.HasPrefix(err.Error(), test.errPrefix)):
+			t.Errorf("%v: expected error %q, got %v", test.args, test.errPrefix, err)
+		case name != test.want:
+			t.Errorf("%v: got %q, expected %q", test.args, name, test.want)
+		}
+	}
+}
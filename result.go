@@ -0,0 +1,47 @@
+package miniflags
+
+// ParseResult holds the results of a parse, as returned by Parse.
+type ParseResult struct {
+	Args       []string               // The non-option arguments, as returned by ParseArgs
+	Terminated []string               // The arguments after the terminator, such as "--", or nil if there was none
+	Unknown    []string               // The unknown options passed through because of AllowUnknown
+	Command    *CommandDef            // The innermost command selected, or nil
+	Sources    map[string]ValueSource // Where each option that got a value was set, by its canonical name
+	Warnings   []string               // The warnings issued during the parse, without "Warning: "
+}
+
+// Parse parses args in the same way as ParseArgs, but returns the results in a
+// ParseResult instead of keeping them in the set, such as for Source, and
+// leaves the package-level Args and UnknownArgs variables unchanged. Sets with
+// their own targets can be parsed by different goroutines at the same time,
+// while parses of one set wait for each other, since they share its targets.
+// The result is returned even if there is an error, with the information
+// gathered before the parse stopped. Sources includes the options of the
+// selected commands and those given in the environment or a config file, not
+// just on the command line. The arguments after a terminator are listed in
+// Terminated whether or not they were assigned to positional arguments.
+// Warnings include only those issued during the parse, such as for an unknown
+// key with WarnUnknownConfig in a config file named by an option; warnings for
+// config files loaded before the parse are only passed to the WarningFunc or
+// written with Emit.
+func (self *OptionSet) Parse(args []string) (*ParseResult, error) {
+	p, _, err := self.parse(args, parseMode{detached: true})
+	return p.result(), err
+}
+
+// Return the results of the parse, including those of the parses of the
+// selected commands.
+func (self *parser) result() *ParseResult {
+	result := &ParseResult{Args: self.args, Unknown: self.unknown,
+		Command: self.leafCommand(), Sources: map[string]ValueSource{}}
+	for p := self; p != nil; p = p.sub {
+		if result.Terminated == nil {
+			result.Terminated = p.terminated
+		}
+		for def, source := range p.sources {
+			result.Sources[def.canonicalName()] = source
+		}
+		result.Warnings = append(result.Warnings, p.warnings...)
+	}
+	return result
+}
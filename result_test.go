@@ -0,0 +1,118 @@
+package miniflags
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func Test_OptionSet_Parse(t *testing.T) {
+	savedEmit := Emit
+	defer func() { Emit = savedEmit }()
+	Emit = func(...interface{}) {}
+
+	var n, level int
+	var src string
+	newSet := func() *OptionSet {
+		return NewOptionSet().
+			Add(Option("n num", &n, "").Env("NUM")).
+			AddCommand(Command("sub", NewOptionSet().
+				Option("level", &level, "").
+				Positional("SRC", &src, ""),
+				func([]string) error { return nil }, "")).
+			AllowUnknown().
+			WarnUnknownConfig()
+	}
+	var tests = []struct {
+		input      []string
+		env        map[string]string
+		wantArgs   []string
+		wantTerm   []string
+		wantUnk    []string
+		wantCmd    string
+		wantSource map[string]ValueSource
+		errPrefix  string
+	}{
+		{[]string{"-n", "1", "sub", "a", "b"}, nil, []string{"b"}, nil, []string{}, "sub",
+			map[string]ValueSource{"num": {SourceCommandLine, "-n"}, "SRC": {SourceCommandLine, "a"}}, ""},
+		{[]string{"--bogus", "sub", "--level=2", "--", "-x", "y"}, map[string]string{"NUM": "3"}, []string{"--", "y"}, []string{"-x", "y"}, []string{"--bogus"}, "sub",
+			map[string]ValueSource{"num": {SourceEnv, "NUM"}, "level": {SourceCommandLine, "--level=2"}, "SRC": {SourceCommandLine, "-x"}}, ""},
+		{[]string{"-n", "x"}, nil, []string{}, nil, []string{}, "",
+			map[string]ValueSource{}, "Error with command line option '-n'"},
+	}
+	for _, test := range tests {
+		restore := fakeEnv(test.env)
+		result, err := newSet().ErrorHandling(ContinueOnError).Parse(test.input)
+		restore()
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+		cmd := ""
+		if result.Command != nil {
+			cmd = result.Command.Name()
+		}
+		want := []interface{}{test.wantArgs, test.wantTerm, test.wantUnk, test.wantCmd, test.wantSource}
+		if m := checkValErr(t, want, []interface{}{result.Args, result.Terminated, result.Unknown, cmd, result.Sources}, "", nil); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tool.ini")
+	if err := os.WriteFile(path, []byte("bogus = 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	set := newSet()
+	set.Option("config", ConfigFileOption(set), "")
+	result, _ := set.Parse([]string{"--config", path, "sub", "a"})
+	if m := checkValErr(t, []string{"Unknown option 'bogus' in config file " + path + ":1"}, result.Warnings, "", nil); m != "" {
+		t.Error(m)
+	}
+	if result, _ = set.Parse([]string{"sub", "a"}); len(result.Warnings) != 0 {
+		t.Errorf("got warnings %q", result.Warnings)
+	}
+}
+
+func Test_OptionSet_Parse_Globals(t *testing.T) {
+	savedArgs, savedUnknown := Args, UnknownArgs
+	defer func() { Args, UnknownArgs = savedArgs, savedUnknown }()
+	Args, UnknownArgs = []string{"old"}, []string{"--old"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var n int
+			set := NewOptionSet().Option("n", &n, "").AllowUnknown()
+			result, err := set.Parse([]string{"-n", "1", "--bogus", "arg"})
+			if m := checkValErr(t, []interface{}{[]string{"arg"}, []string{"--bogus"}, 1},
+				[]interface{}{result.Args, result.Unknown, n}, "", err); m != "" {
+				t.Error(m)
+			}
+		}()
+	}
+	wg.Wait()
+	if m := checkValErr(t, []interface{}{[]string{"old"}, []string{"--old"}}, []interface{}{Args, UnknownArgs}, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_Parse_WarningsReset(t *testing.T) {
+	var old string
+	set := NewOptionSet().
+		Add(Option("old", &old, "").Deprecated("use --new")).
+		WarningFunc(func(string) {})
+	for i := 0; i < 3; i++ {
+		set.ParseArgs([]string{"--old=x"})
+	}
+	result, err := set.Parse([]string{})
+	if m := checkValErr(t, []string(nil), result.Warnings, "", err); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,247 @@
+package miniflags
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Validator is a check on the value of an option, attached with Validate.
+// It is called with the option's name as shown in messages, such as
+// "--percent" or "SRC", and the value after conversion to the type of the
+// target: an int for an *int target, a string for a *string or *[]string
+// target or a setter function, and so on. It returns an error describing the
+// problem if the value is not acceptable, such as "value 200 for --percent
+// must be between 0 and 100", or nil.
+type Validator func(name string, value interface{}) error
+
+// Validate attaches validators to this option. Each time a parameter is
+// given, after it has been converted and before the target is set, the
+// validators are called in order, and the first error is reported as an
+// error with the option. The value of a bool or an option without a
+// parameter is not validated. Returns self so that calls can be chained.
+func (self *OptionDef) Validate(validators ...Validator) *OptionDef {
+	self.validators = append(self.validators, validators...)
+	return self
+}
+
+// Check a converted value against the validators of this option before it
+// is stored in the target. If convErr is not nil, the conversion failed and
+// convErr is returned. Otherwise returns the error from the first validator
+// that fails, or nil.
+func (self *OptionDef) check(value interface{}, convErr error) error {
+	if convErr != nil {
+		return convErr
+	}
+	name := self.names
+	if !self.positional {
+		name = self.displayName()
+	}
+	for _, validator := range self.validators {
+		if err := validator(name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Convert a numeric value to float64 for comparisons. Returns false if the
+// value is not a number.
+func toFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case int:
+		return float64(value), true
+	case int64:
+		return float64(value), true
+	case uint:
+		return float64(value), true
+	case uint64:
+		return float64(value), true
+	case float64:
+		return value, true
+	}
+	return 0, false
+}
+
+// Range returns a Validator restricting a numeric value to the range from
+// min to max inclusive. Values that are not numbers are accepted.
+func Range(min, max float64) Validator {
+	return func(name string, value interface{}) error {
+		if f, ok := toFloat(value); ok && (f < min || f > max) {
+			return fmt.Errorf("value %v for %s must be between %v and %v", value, name, min, max)
+		}
+		return nil
+	}
+}
+
+// NonEmpty returns a Validator rejecting an empty string, such as the value
+// of "--name=" or of an environment variable that is set but empty, with an
+// error such as "option '--name' requires a non-empty value". Values that
+// are not strings are accepted.
+func NonEmpty() Validator {
+	return func(name string, value interface{}) error {
+		if value == "" {
+			return fmt.Errorf("option '%s' requires a non-empty value", name)
+		}
+		return nil
+	}
+}
+
+// OneOf returns a Validator restricting a string value to one of choices.
+// Values that are not strings are accepted.
+func OneOf(choices ...string) Validator {
+	return func(name string, value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		for _, choice := range choices {
+			if s == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %s for %s must be one of: %s", s, name, strings.Join(choices, ", "))
+	}
+}
+
+// OneOfInts returns a Validator restricting a numeric value to one of
+// choices, with an error such as "value 5 for --level must be one of: 0, 1,
+// 9". Values that are not numbers are accepted. Use IntAlternativesOption
+// instead to also list the choices in the help text.
+func OneOfInts(choices ...int) Validator {
+	return func(name string, value interface{}) error {
+		f, ok := toFloat(value)
+		if !ok {
+			return nil
+		}
+		list := []string{}
+		for _, choice := range choices {
+			if float64(choice) == f {
+				return nil
+			}
+			list = append(list, strconv.Itoa(choice))
+		}
+		return fmt.Errorf("value %v for %s must be one of: %s", value, name, strings.Join(list, ", "))
+	}
+}
+
+// URLScheme returns a Validator requiring a string value to be an absolute
+// URL with a host and one of the given schemes, compared ignoring case, so
+// that for example an endpoint option can be limited to "https". If no
+// schemes are given, any scheme is accepted. Values that are not strings are
+// accepted.
+func URLScheme(schemes ...string) Validator {
+	requirement := "an absolute URL"
+	if len(schemes) > 0 {
+		requirement = fmt.Sprintf("a URL with scheme %s", strings.Join(schemes, " or "))
+	}
+	return func(name string, value interface{}) error {
+		s, ok := value.(string)
+		if !ok {
+			return nil
+		}
+		u, err := url.Parse(s)
+		valid := err == nil && u.Scheme != "" && u.Host != ""
+		if valid && len(schemes) > 0 {
+			valid = false
+			for _, scheme := range schemes {
+				valid = valid || strings.EqualFold(u.Scheme, scheme)
+			}
+		}
+		if !valid {
+			return fmt.Errorf("value %s for %s must be %s", s, name, requirement)
+		}
+		return nil
+	}
+}
+
+// Range restricts the value of this option, which must have a numeric
+// target, to the range from min to max inclusive, with the Range validator.
+// A value outside the range is reported as an error such as "value 200 for
+// --percent must be between 0 and 100" when it is parsed, and the target is
+// not changed. Returns self so that calls can be chained.
+func (self *OptionDef) Range(min, max float64) *OptionDef {
+	switch self.target.(type) {
+	case *int, *int64, *uint, *uint64, *float64:
+	default:
+		if self.setupError == nil {
+			self.setupError = fmt.Errorf("Range requires a numeric target for option '%s'", self.formatOptionNames())
+		}
+	}
+	return self.Validate(Range(min, max))
+}
+
+// Matches restricts the value of this option, which must have a string
+// target such as *string or *[]string, to strings matching the regular
+// expression pattern. Use "^" and "$" to match the whole value. A value that
+// doesn't match is reported as an error such as "value 1.x for --version
+// must match ^[0-9]+\.[0-9]+$" when it is parsed. An invalid pattern is
+// reported when ParseArgs is called. Returns self so that calls can be
+// chained.
+func (self *OptionDef) Matches(pattern string) *OptionDef {
+	re, err := regexp.Compile(pattern)
+	if self.setupError == nil {
+		switch {
+		case err != nil:
+			self.setupError = fmt.Errorf("Invalid pattern for option '%s': %v", self.formatOptionNames(), err)
+		case !self.hasStringValue():
+			self.setupError = fmt.Errorf("Matches requires a string target for option '%s'", self.formatOptionNames())
+		}
+	}
+	return self.Validate(func(name string, value interface{}) error {
+		if s, ok := value.(string); ok && re != nil && !re.MatchString(s) {
+			return fmt.Errorf("value %s for %s must match %s", s, name, pattern)
+		}
+		return nil
+	})
+}
+
+// Unique prevents the same value from appearing twice in the list of this
+// option, which must have a *[]string target, as when the same "--tag" is
+// given twice. If dedupe is false, a repeated value is reported as an error
+// such as "value web for --tag was already given" when it is parsed. If
+// dedupe is true, a repeated value is dropped instead, with a warning such as
+// "Repeated value 'web' for --tag ignored", so the list keeps the first
+// occurrence of each value. Returns self so that calls can be
+// chained.
+func (self *OptionDef) Unique(dedupe bool) *OptionDef {
+	target, ok := self.target.(*[]string)
+	if !ok {
+		if self.setupError == nil {
+			self.setupError = fmt.Errorf("Unique requires a list target for option '%s'", self.formatOptionNames())
+		}
+		return self
+	}
+	if dedupe {
+		self.dedupe = true
+		return self
+	}
+	return self.Validate(func(name string, value interface{}) error {
+		if s, ok := value.(string); ok && containsString(*target, s) {
+			return fmt.Errorf("value %s for %s was already given", s, name)
+		}
+		return nil
+	})
+}
+
+// Check whether list contains s.
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Check whether the values of this option are validated as strings, rather
+// than as numbers or not at all.
+func (self *OptionDef) hasStringValue() bool {
+	switch self.target.(type) {
+	case *string, *[]string, func(string), func(string) error, Setter:
+		return true
+	}
+	return false
+}
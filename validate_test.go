@@ -0,0 +1,218 @@
+package miniflags
+
+import (
+	"fmt"
+	"testing"
+)
+
+func Test_OptionDef_Range(t *testing.T) {
+	var percent, count int
+	var ratio float64
+	var tests = []struct {
+		input     []string
+		want      []interface{}
+		errPrefix string
+	}{
+		{[]string{}, []interface{}{-1, -1, -1.0}, ""},
+		{[]string{"--percent=0", "--ratio", "0.5", "7"}, []interface{}{0, 7, 0.5}, ""},
+		{[]string{"--percent=100"}, []interface{}{100, -1, -1.0}, ""},
+		{[]string{"--percent=200"}, []interface{}{-1, -1, -1.0}, "Error with command line option '--percent=200': value 200 for --percent must be between 0 and 100"},
+		{[]string{"-p", "-5"}, []interface{}{-1, -1, -1.0}, "Error with command line option '-p': value -5 for --percent must be between 0 and 100"},
+		{[]string{"--ratio=1.5"}, []interface{}{-1, -1, -1.0}, "Error with command line option '--ratio=1.5': value 1.5 for --ratio must be between 0 and 1"},
+		{[]string{"11"}, []interface{}{-1, -1, -1.0}, "Error with argument COUNT '11': value 11 for COUNT must be between 1 and 10"},
+	}
+	for _, test := range tests {
+		percent, count, ratio = -1, -1, -1
+		_, err := NewOptionSet().
+			Add(Option("p percent", &percent, "").Range(0, 100)).
+			Add(Option("ratio", &ratio, "").Range(0, 1)).
+			Add(Positional("COUNT", &count, "").Range(1, 10).Arity(0, 1)).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, []interface{}{percent, count, ratio}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	var name string
+	_, err := NewOptionSet().Add(Option("name", &name, "").Range(0, 1)).ParseArgs([]string{})
+	if m := checkValErr(t, nil, nil, "Range requires a numeric target for option '--name'", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionDef_Matches(t *testing.T) {
+	var version string
+	var hosts []string
+	var tests = []struct {
+		input     []string
+		want      []interface{}
+		errPrefix string
+	}{
+		{[]string{"--version=1.2", "--host=a.example", "--host=b"}, []interface{}{"1.2", []string{"a.example", "b"}}, ""},
+		{[]string{"--version=1.x"}, []interface{}{"", []string(nil)}, `Error with command line option '--version=1.x': value 1.x for --version must match ^[0-9]+\.[0-9]+$`},
+		{[]string{"--host=a", "--host=-bad"}, []interface{}{"", []string{"a"}}, "Error with command line option '--host=-bad': value -bad for --host must match ^[a-z]"},
+	}
+	for _, test := range tests {
+		version, hosts = "", nil
+		_, err := NewOptionSet().
+			Add(Option("version", &version, "").Matches(`^[0-9]+\.[0-9]+$`)).
+			Add(Option("host", &hosts, "").Matches(`^[a-z][a-z.]*$`)).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, []interface{}{version, hosts}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	var n int
+	var errTests = []struct {
+		def       *OptionDef
+		errPrefix string
+	}{
+		{Option("version", &version, "").Matches("("), "Invalid pattern for option '--version': error parsing regexp"},
+		{Option("n", &n, "").Matches("x"), "Matches requires a string target for option '-n'"},
+	}
+	for _, test := range errTests {
+		_, err := NewOptionSet(test.def).ParseArgs([]string{})
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(m)
+		}
+	}
+}
+
+func Test_OptionDef_Unique(t *testing.T) {
+	var tags, hosts []string
+	var tests = []struct {
+		input     []string
+		want      []interface{}
+		errPrefix string
+	}{
+		{[]string{"--tag=a", "--tag=b", "--host=x", "--host=y"}, []interface{}{[]string{"a", "b"}, []string{"x", "y"}}, ""},
+		{[]string{"--tag=a", "--tag=b", "--tag=a"}, []interface{}{[]string{"a", "b"}, []string(nil)}, "Error with command line option '--tag=a': value a for --tag was already given"},
+		{[]string{"--host=x", "--host=y", "--host=x", "--host=y"}, []interface{}{[]string(nil), []string{"x", "y"}}, ""},
+	}
+	for _, test := range tests {
+		tags, hosts = nil, nil
+		_, err := NewOptionSet().
+			Add(Option("tag", &tags, "").Unique(false)).
+			Add(Option("host", &hosts, "").Unique(true)).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, []interface{}{tags, hosts}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	var name string
+	_, err := NewOptionSet().Add(Option("name", &name, "").Unique(true)).ParseArgs([]string{})
+	if m := checkValErr(t, nil, nil, "Unique requires a list target for option '--name'", err); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_NonEmpty(t *testing.T) {
+	var name string
+	var tags []string
+	var tests = []struct {
+		input     []string
+		env       map[string]string
+		want      []interface{}
+		errPrefix string
+	}{
+		{[]string{}, nil, []interface{}{"unset", []string(nil)}, ""},
+		{[]string{"--name=bob", "--tag", "a"}, nil, []interface{}{"bob", []string{"a"}}, ""},
+		{[]string{"--name="}, nil, []interface{}{"unset", []string(nil)}, "Error with command line option '--name=': option '--name' requires a non-empty value"},
+		{[]string{"--name", ""}, nil, []interface{}{"unset", []string(nil)}, "Error with command line option '--name': option '--name' requires a non-empty value"},
+		{[]string{"--tag="}, nil, []interface{}{"unset", []string(nil)}, "Error with command line option '--tag=': option '--tag' requires a non-empty value"},
+		{[]string{}, map[string]string{"NAME": ""}, []interface{}{"unset", []string(nil)}, "Error with environment variable 'NAME': option '--name' requires a non-empty value"},
+	}
+	for _, test := range tests {
+		name, tags = "unset", nil
+		restore := fakeEnv(test.env)
+		_, err := NewOptionSet().
+			Add(Option("name", &name, "").Env("NAME").Validate(NonEmpty())).
+			Add(Option("tag", &tags, "").Validate(NonEmpty())).
+			ParseArgs(test.input)
+		restore()
+		if m := checkValErr(t, test.want, []interface{}{name, tags}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_OneOfInts(t *testing.T) {
+	var level int
+	var tests = []struct {
+		input     []string
+		want      int
+		errPrefix string
+	}{
+		{[]string{}, -1, ""},
+		{[]string{"--level=9"}, 9, ""},
+		{[]string{"--level=0x1"}, 1, ""},
+		{[]string{"--level=5"}, -1, "Error with command line option '--level=5': value 5 for --level must be one of: 0, 1, 9"},
+	}
+	for _, test := range tests {
+		level = -1
+		_, err := NewOptionSet().
+			Add(Option("level", &level, "").Validate(OneOfInts(0, 1, 9))).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, level, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
+
+func Test_URLScheme(t *testing.T) {
+	var tests = []struct {
+		validator Validator
+		value     interface{}
+		errPrefix string
+	}{
+		{URLScheme("https"), "https://example.com/api", ""},
+		{URLScheme("https"), "HTTPS://example.com", ""},
+		{URLScheme("https"), "http://example.com", "value http://example.com for --endpoint must be a URL with scheme https"},
+		{URLScheme("https", "wss"), "ftp://example.com", "value ftp://example.com for --endpoint must be a URL with scheme https or wss"},
+		{URLScheme("https"), "example.com", "value example.com for --endpoint must be a URL with scheme https"},
+		{URLScheme("https"), "https:///path", "value https:///path for --endpoint must be a URL with scheme https"},
+		{URLScheme(), "gopher://example.com", ""},
+		{URLScheme(), "/path", "value /path for --endpoint must be an absolute URL"},
+		{URLScheme(), "http://[::1", "value http://[::1 for --endpoint must be an absolute URL"},
+		{URLScheme("https"), 3, ""},
+	}
+	for _, test := range tests {
+		err := test.validator("--endpoint", test.value)
+		if m := checkValErr(t, nil, nil, test.errPrefix, err); m != "" {
+			t.Error(test.value, m)
+		}
+	}
+}
+
+func Test_OptionDef_Validate(t *testing.T) {
+	var mode string
+	var level int
+	even := func(name string, value interface{}) error {
+		if value.(int)%2 != 0 {
+			return fmt.Errorf("value %v for %s must be even", value, name)
+		}
+		return nil
+	}
+	var tests = []struct {
+		input     []string
+		want      []interface{}
+		errPrefix string
+	}{
+		{[]string{"--mode=fast", "--level=4"}, []interface{}{"fast", 4}, ""},
+		{[]string{"--mode=quick"}, []interface{}{"", 0}, "Error with command line option '--mode=quick': value quick for --mode must be one of: fast, slow"},
+		{[]string{"--level=3"}, []interface{}{"", 0}, "Error with command line option '--level=3': value 3 for --level must be even"},
+		{[]string{"--level=12"}, []interface{}{"", 0}, "Error with command line option '--level=12': value 12 for --level must be between 0 and 10"},
+	}
+	for _, test := range tests {
+		mode, level = "", 0
+		_, err := NewOptionSet().
+			Add(Option("mode", &mode, "").Validate(OneOf("fast", "slow"))).
+			Add(Option("level", &level, "").Validate(Range(0, 10), even)).
+			ParseArgs(test.input)
+		if m := checkValErr(t, test.want, []interface{}{mode, level}, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+}
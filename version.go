@@ -0,0 +1,41 @@
+package miniflags
+
+import "runtime/debug"
+
+// Version is the version string of the program, shown by the automatic
+// version option. It is empty by default, which disables the option; see
+// AutoVersion.
+var Version string
+
+// AutoVersion enables the automatic generation of version options, in the
+// same way as AutoHelp. If Version is not empty, neither "-V" nor "--version"
+// is defined, and AutoVersion is true, then these options are added to the
+// outermost OptionSet. Their action is to pass Version to Emit and return
+// ErrVersion from ParseArgs, in the same way as the automatic help options.
+var AutoVersion = true
+
+// BuildVersion returns the version of the program's main module recorded by
+// the Go toolchain, such as "v1.2.3", for use as Version. If the version is
+// not known, as for a program built from a source tree, the VCS revision is
+// returned instead if it was recorded, or else "(devel)".
+func BuildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && setting.Value != "" {
+			return setting.Value
+		}
+	}
+	return "(devel)"
+}
+
+// Check whether the automatic version option is enabled for this set.
+func (self *OptionSet) autoVersion() bool {
+	return AutoVersion && Version != "" && self.parent == nil &&
+		self.lookupDef("V") == nil && self.lookupDef("version") == nil
+}
@@ -0,0 +1,53 @@
+package miniflags
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_AutoVersion(t *testing.T) {
+	savedVersion, savedEmit := Version, Emit
+	defer func() { Version, Emit = savedVersion, savedEmit }()
+	var emitted []interface{}
+	Emit = func(a ...interface{}) { emitted = append(emitted, a...) }
+	var flag bool
+	var tests = []struct {
+		version   string
+		oset      *OptionSet
+		input     string
+		want      []interface{}
+		errPrefix string
+	}{
+		{"1.2.3", NewOptionSet(), "--version", []interface{}{"1.2.3"}, "Version requested"},
+		{"1.2.3", NewOptionSet(), "-V", []interface{}{"1.2.3"}, "Version requested"},
+		{"", NewOptionSet(), "-V", nil, "Unknown option '-V'"},
+		{"1.2.3", NewOptionSet(Option("V", &flag, "")), "--version", nil, "Unknown option '--version'"},
+	}
+	for _, test := range tests {
+		Version, emitted = test.version, nil
+		err := test.oset.Execute(context.Background(), []string{test.input})
+		if m := checkValErr(t, test.want, emitted, test.errPrefix, err); m != "" {
+			t.Error(test.input, m)
+		}
+	}
+
+	Version, emitted = "1.2.3", nil
+	args, err := NewOptionSet().ParseArgs([]string{"--version", "x"})
+	if m := checkValErr(t, []interface{}{"1.2.3"}, emitted, "Version requested", err); m != "" {
+		t.Error("ParseArgs", m)
+	}
+	if len(args) != 0 {
+		t.Error("ParseArgs returned arguments", args)
+	}
+
+	want := []string{
+		"  -h, --help        Print this help message and exit",
+		"  -V, --version     Print the version and exit",
+	}
+	if m := checkValErr(t, want, NewOptionSet().FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+	if BuildVersion() == "" {
+		t.Error("BuildVersion returned an empty string")
+	}
+}
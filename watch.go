@@ -0,0 +1,174 @@
+package miniflags
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"time"
+)
+
+// ReloadConfig reads the config file at path again with LoadConfig, after
+// forgetting the values previously read from it, and applies the new values
+// to the targets of the options, so that a long-running program can pick up
+// changes. An option whose value came from a source with a higher precedence
+// than config files in the last parse, such as the command line, is left
+// alone. An option whose key was removed from the file is reset to its
+// default, or to a value from another source with a lower precedence. Returns
+// the canonical names of the options whose values changed. If the file can't
+// be read, the previous values are kept and the error is returned.
+//
+// ReloadConfig holds the same lock as a parse of the set, so it waits for a
+// parse in progress in another goroutine, such as one started by
+// ParseArgsWith, and must not be called during a parse of the set. The
+// targets are set by the calling goroutine; a program that reads them in
+// other goroutines must synchronize with it.
+func (self *OptionSet) ReloadConfig(path string) ([]string, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	previous := map[*OptionDef]*configValue{}
+	for def, value := range self.config {
+		if value.file == path {
+			previous[def] = value
+			delete(self.config, def)
+		}
+	}
+	if err := self.LoadConfig(path); err != nil {
+		for def, value := range previous {
+			if current := self.config[def]; current == nil || current.file == path {
+				self.config[def] = value
+			}
+		}
+		return nil, err
+	}
+	order := self.precedence
+	if order == nil {
+		order = defaultPrecedence
+	}
+	if self.sources == nil {
+		self.sources = map[*OptionDef]ValueSource{}
+	}
+	// apply the values as a parse would, updating the results of the last one
+	p := &parser{set: self, sources: self.sources, values: map[*OptionDef][]string{}, config: self.config}
+	changed := []string{}
+	for _, def := range self.list {
+		old, loaded := previous[def], self.config[def]
+		if (old == nil && (loaded == nil || loaded.file != path)) || self.outranksConfig(self.sources[def].Kind, order) {
+			continue
+		}
+		before := def.currentValue()
+		def.restoreDefault()
+		self.sources[def] = ValueSource{}
+		found := false
+		var err error
+		for _, source := range order[indexOfSource(order, SourceConfig):] {
+			switch source {
+			case SourceEnv:
+				found, err = p.applyEnv(def, false)
+			case SourceConfig:
+				found, err = p.applyConfig(def, false)
+			}
+			if err != nil {
+				return changed, err
+			}
+			if found {
+				break
+			}
+		}
+		if after := def.currentValue(); before != after || (before == nil && !reflect.DeepEqual(old.valueList(), loaded.valueList())) {
+			changed = append(changed, def.canonicalName())
+		}
+	}
+	return changed, nil
+}
+
+// WatchConfig calls ReloadConfig for the config file at path whenever the
+// program receives SIGHUP, or the modification time of the file changes, which
+// is checked every interval. If interval is zero, only SIGHUP is used. On
+// systems without SIGHUP, such as Windows, only the interval is used. After
+// each reload, any changed function is called with the names of the options
+// whose values changed and any error; it is not called when nothing changed.
+// WatchConfig returns when ctx is done, so it is usually run in its own
+// goroutine:
+//
+//	go oset.WatchConfig(ctx, path, 5*time.Second, func(names []string, err error) {
+//		...
+//	})
+//
+// See ReloadConfig about synchronizing access to the targets.
+func (self *OptionSet) WatchConfig(ctx context.Context, path string, interval time.Duration,
+	changed func(names []string, err error)) {
+	hangup, stop := notifyHangup()
+	defer stop()
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	modTime := fileModTime(path)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+		case <-tick:
+			if latest := fileModTime(path); latest.Equal(modTime) {
+				continue
+			}
+		}
+		modTime = fileModTime(path)
+		names, err := self.ReloadConfig(path)
+		if changed != nil && (len(names) > 0 || err != nil) {
+			changed(names, err)
+		}
+	}
+}
+
+// Return the modification time of a file, or the zero time if it can't be
+// found.
+func fileModTime(path string) time.Time {
+	if info, err := os.Stat(path); err == nil {
+		return info.ModTime()
+	}
+	return time.Time{}
+}
+
+// Check whether a value from source takes precedence over config files in
+// the given order.
+func (self *OptionSet) outranksConfig(source Source, order []Source) bool {
+	switch source {
+	case SourceCommandLine, SourceEnv:
+		return indexOfSource(order, source) < indexOfSource(order, SourceConfig)
+	case SourcePrompt:
+		return true
+	}
+	return false
+}
+
+// Return the index of source in order, or len(order) if it isn't there.
+func indexOfSource(order []Source, source Source) int {
+	for i, s := range order {
+		if s == source {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// Return the value of a variable target formatted for comparison, or nil if
+// the target is a function.
+func (self *OptionDef) currentValue() interface{} {
+	if self.defaultValue == nil {
+		return nil
+	}
+	return fmt.Sprint(reflect.ValueOf(self.target).Elem().Interface())
+}
+
+// Return the values of a config value, or nil if there is none.
+func (self *configValue) valueList() []string {
+	if self == nil {
+		return nil
+	}
+	return self.values
+}
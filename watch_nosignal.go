@@ -0,0 +1,11 @@
+//go:build js || wasip1 || windows || plan9
+
+package miniflags
+
+import "os"
+
+// There is no SIGHUP on this system, so return a channel that never receives
+// anything.
+func notifyHangup() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
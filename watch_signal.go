@@ -0,0 +1,17 @@
+//go:build !js && !wasip1 && !windows && !plan9
+
+package miniflags
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Return a channel that receives SIGHUP, and a function that stops the
+// delivery of the signal to it.
+func notifyHangup() (<-chan os.Signal, func()) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	return hangup, func() { signal.Stop(hangup) }
+}
@@ -0,0 +1,131 @@
+package miniflags
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_OptionSet_ReloadConfig(t *testing.T) {
+	var name, color, size string
+	var count int
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tool.ini")
+	write := func(text string) {
+		if err := os.WriteFile(path, []byte(text), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("name = bob\ncolor = red\nsize = big\n")
+	oset := NewOptionSet().
+		Option("name", &name, "").
+		Option("color", &color, "").
+		Option("size", &size, "").
+		Option("count", func(string) { count++ }, "")
+	if err := oset.LoadINI(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oset.ParseArgs([]string{"--name=sue"}); err != nil {
+		t.Fatal(err)
+	}
+
+	write("name = ann\ncolor = blue\ncount = 1\n")
+	names, err := oset.ReloadConfig(path)
+	if m := checkValErr(t, []string{"color", "size", "count"}, names, "", err); m != "" {
+		t.Error(m)
+	}
+	got := []interface{}{name, color, size, count, oset.Source("size").Kind}
+	if m := checkValErr(t, []interface{}{"sue", "blue", "", 1, SourceDefault}, got, "", nil); m != "" {
+		t.Error(m)
+	}
+
+	names, err = oset.ReloadConfig(path)
+	if m := checkValErr(t, []string{}, names, "", err); m != "" {
+		t.Error(m)
+	}
+
+	write("color = \n[bad")
+	_, err = oset.ReloadConfig(path)
+	if m := checkValErr(t, "blue", color, "Invalid section line in config file", err); m != "" {
+		t.Error(m)
+	}
+	if m := checkValErr(t, "blue", oset.config[oset.lookupDef("color")].values[0], "", nil); m != "" {
+		t.Error(m)
+	}
+
+	// reloading in another goroutine waits for the parses of the set
+	write("color = green\n")
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 10; i++ {
+			oset.ReloadConfig(path)
+		}
+		close(done)
+	}()
+	for i := 0; i < 10; i++ {
+		oset.ParseArgsWith([]string{"--name=sue"}, func(*ParseResult, error) error { return nil })
+	}
+	<-done
+	if m := checkValErr(t, "green", color, "", nil); m != "" {
+		t.Error(m)
+	}
+}
+
+func Test_OptionSet_WatchConfig(t *testing.T) {
+	var color string
+	dir, err := os.MkdirTemp("", "miniflags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tool.ini")
+	if err := os.WriteFile(path, []byte("color = red\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	oset := NewOptionSet().Option("color", &color, "")
+	if err := oset.LoadINI(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := oset.ParseArgs([]string{}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	reloaded := make(chan []string, 1)
+	done := make(chan bool)
+	go func() {
+		oset.WatchConfig(ctx, path, time.Millisecond, func(names []string, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			reloaded <- names
+		})
+		done <- true
+	}()
+	time.Sleep(10 * time.Millisecond)
+	if err := os.WriteFile(path, []byte("color = blue\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case names := <-reloaded:
+		if m := checkValErr(t, []string{"color"}, names, "", nil); m != "" {
+			t.Error(m)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("config file was not reloaded")
+	}
+	cancel()
+	<-done
+	if m := checkValErr(t, "blue", color, "", nil); m != "" {
+		t.Error(m)
+	}
+}
@@ -0,0 +1,39 @@
+package miniflags
+
+import "testing"
+
+func Test_displayWidth(t *testing.T) {
+	var tests = []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"--name", 6},
+		{"--名前", 6},
+		{"ｆｕｌｌ", 8},
+		{"한국어", 6},
+		{"é", 1},
+		{"a​b", 2},
+	}
+	for _, test := range tests {
+		if got := displayWidth(test.text); got != test.want {
+			t.Errorf("%q: want %d, got %d", test.text, test.want, got)
+		}
+	}
+}
+
+func Test_OptionSet_FormatOptionsHelp_Wide(t *testing.T) {
+	var name, size string
+	oset := NewOptionSet().
+		Option("名前", &name, "=名前; 名前を設定する").
+		Option("size", &size, "=SIZE; Set the size").
+		Add(Option("h", func() {}, "").Hide()).
+		HelpColumn(-1)
+	want := []string{
+		"  --名前=名前  名前を設定する",
+		"  --size=SIZE  Set the size",
+	}
+	if m := checkValErr(t, want, oset.FormatOptionsHelp(), "", nil); m != "" {
+		t.Error(m)
+	}
+}